@@ -0,0 +1,246 @@
+// catalogcheck 校验发展卡与贵族目录的数据不变量，发现问题时以非零状态退出
+//
+//	go run ./cmd/catalogcheck [-catalog path/to/catalog.json] [-images ../frontend/public/images]
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"splendor-duel-backend/internal/game"
+	"splendor-duel-backend/internal/models"
+)
+
+// 5种一般颜色
+var baseColors = []models.GemType{models.GemWhite, models.GemBlue, models.GemGreen, models.GemRed, models.GemBlack}
+
+// 轮盘位置符号，与 colorWheel 每行的列一一对应
+const wheelSymbols = "ZXCVB"
+
+type checker struct {
+	problems []string
+}
+
+func (c *checker) fail(format string, args ...any) {
+	c.problems = append(c.problems, fmt.Sprintf(format, args...))
+}
+
+func main() {
+	catalogPath := flag.String("catalog", "", "要校验的卡牌目录文件（JSON），为空时校验内置目录")
+	imagesDir := flag.String("images", "../frontend/public/images", "前端 public/images 目录，为空时跳过图片检查")
+	level1 := flag.Int("level1", 30, "等级1发展卡的期望数量")
+	level2 := flag.Int("level2", 24, "等级2发展卡的期望数量")
+	level3 := flag.Int("level3", 13, "等级3发展卡的期望数量")
+	flag.Parse()
+
+	var (
+		catalog *game.Catalog
+		err     error
+	)
+	if *catalogPath != "" {
+		catalog, err = game.LoadCatalogFile(*catalogPath)
+	} else {
+		catalog, err = game.ParseCatalog(game.DefaultCatalogJSON())
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "目录加载失败:\n%v\n", err)
+		os.Exit(1)
+	}
+
+	c := &checker{}
+	cards := catalog.DevelopmentCards()
+	c.checkColorWheel()
+	c.checkLevelCounts(cards, map[models.CardLevel]int{models.Level1: *level1, models.Level2: *level2, models.Level3: *level3})
+	c.checkUniqueIDs(cards, catalog.Nobles)
+	c.checkWheelFormulas(catalog.WheelCards)
+	c.checkCosts(cards)
+	c.checkEffects(cards)
+	if *imagesDir != "" {
+		c.checkImages(*imagesDir, cards, catalog.Nobles)
+	}
+
+	if len(c.problems) > 0 {
+		for _, problem := range c.problems {
+			fmt.Println("✗", problem)
+		}
+		fmt.Printf("发现 %d 个问题\n", len(c.problems))
+		os.Exit(1)
+	}
+	fmt.Printf("目录校验通过：%d 张发展卡，%d 位贵族\n", len(cards), len(catalog.Nobles))
+}
+
+// 轮盘每行、每列都必须是5种颜色的排列，C 列（卡牌本身颜色）才能唯一确定所在行
+func (c *checker) checkColorWheel() {
+	wheel := game.ColorWheel()
+	if len(wheel) != len(baseColors) {
+		c.fail("轮盘应有 %d 行，实际 %d 行", len(baseColors), len(wheel))
+		return
+	}
+	for i, row := range wheel {
+		if len(row) != len(wheelSymbols) {
+			c.fail("轮盘第 %d 行应有 %d 列，实际 %d 列", i+1, len(wheelSymbols), len(row))
+			return
+		}
+		if !distinctBaseColors(row) {
+			c.fail("轮盘第 %d 行不是5种颜色的排列: %v", i+1, row)
+		}
+	}
+	for col := range wheelSymbols {
+		column := make([]models.GemType, len(wheel))
+		for i, row := range wheel {
+			column[i] = row[col]
+		}
+		if !distinctBaseColors(column) {
+			c.fail("轮盘 %c 列不是5种颜色的排列: %v", wheelSymbols[col], column)
+		}
+	}
+}
+
+// 各等级的卡牌数量
+func (c *checker) checkLevelCounts(cards []game.DevelopmentCardData, expected map[models.CardLevel]int) {
+	counts := make(map[models.CardLevel]int)
+	for _, card := range cards {
+		counts[card.Level]++
+	}
+	for level := models.Level1; level <= models.Level3; level++ {
+		if counts[level] != expected[level] {
+			c.fail("等级 %d 应有 %d 张发展卡，实际 %d 张", level, expected[level], counts[level])
+		}
+	}
+}
+
+// 发展卡与贵族的ID唯一，且两者之间不冲突
+func (c *checker) checkUniqueIDs(cards []game.DevelopmentCardData, nobles []models.NobleCard) {
+	seen := make(map[string]bool)
+	for _, card := range cards {
+		if seen[card.ID] {
+			c.fail("ID %s 重复", card.ID)
+		}
+		seen[card.ID] = true
+	}
+	for _, noble := range nobles {
+		if seen[noble.ID] {
+			c.fail("ID %s 重复", noble.ID)
+		}
+		seen[noble.ID] = true
+	}
+}
+
+// 每个轮盘公式按5种卡牌颜色解析后，公式中的每个位置符号都映射到5种不同的颜色
+func (c *checker) checkWheelFormulas(templates []game.WheelCardTemplate) {
+	for _, tmpl := range templates {
+		symbols := make(map[rune][]models.GemType)
+		for _, part := range strings.Fields(tmpl.Formula) {
+			symbol := rune(part[len(part)-1])
+			if strings.ContainsRune(wheelSymbols, symbol) {
+				symbols[symbol] = nil
+			}
+		}
+
+		for _, color := range baseColors {
+			cost, err := game.ResolveWheelFormula(tmpl.Formula, color)
+			if err != nil {
+				c.fail("模板 %s 的公式 %q 无法按 %s 解析: %v", tmpl.Code, tmpl.Formula, color, err)
+				continue
+			}
+			if _, ok := cost[color]; ok != strings.ContainsRune(tmpl.Formula, 'C') {
+				c.fail("模板 %s 的公式 %q 按 %s 解析时 C 位置与卡牌颜色不一致", tmpl.Code, tmpl.Formula, color)
+			}
+			for symbol := range symbols {
+				symbols[symbol] = append(symbols[symbol], wheelColor(color, symbol))
+			}
+		}
+
+		for symbol, colors := range symbols {
+			if !distinctBaseColors(colors) {
+				c.fail("模板 %s 的 %c 位置在5种卡牌颜色下没有得到5种不同的颜色: %v", tmpl.Code, symbol, colors)
+			}
+		}
+	}
+}
+
+// 所有发展卡都有费用
+func (c *checker) checkCosts(cards []game.DevelopmentCardData) {
+	for _, card := range cards {
+		total := 0
+		for _, count := range card.Cost {
+			total += count
+		}
+		if total <= 0 {
+			c.fail("发展卡 %s 没有费用", card.ID)
+		}
+	}
+}
+
+// 效果组合必须是引擎能结算的：百搭只能在灰色卡上，额外token需要卡牌有颜色，同一效果不重复
+func (c *checker) checkEffects(cards []game.DevelopmentCardData) {
+	for _, card := range cards {
+		seen := make(map[models.CardEffect]bool)
+		for _, effect := range card.Effects {
+			if seen[effect] {
+				c.fail("发展卡 %s 的效果 %s 重复", card.ID, effect)
+			}
+			seen[effect] = true
+		}
+		if seen[models.Wildcard] && card.Color != models.GemGray {
+			c.fail("发展卡 %s 有百搭效果但颜色为 %s", card.ID, card.Color)
+		}
+		if seen[models.ExtraToken] && card.Color == models.GemGray {
+			c.fail("发展卡 %s 为灰色，无法结算额外token效果", card.ID)
+		}
+	}
+}
+
+// 引用的图片都存在于前端 public/images 下
+func (c *checker) checkImages(imagesDir string, cards []game.DevelopmentCardData, nobles []models.NobleCard) {
+	var missing []string
+	check := func(owner, imagePath string) {
+		if !strings.HasPrefix(imagePath, "/images/") {
+			c.fail("%s 的图片路径 %s 不在 /images/ 下", owner, imagePath)
+			return
+		}
+		file := filepath.Join(imagesDir, filepath.FromSlash(strings.TrimPrefix(imagePath, "/images/")))
+		if _, err := os.Stat(file); err != nil {
+			missing = append(missing, fmt.Sprintf("%s 的图片不存在: %s", owner, file))
+		}
+	}
+	for _, card := range cards {
+		check("发展卡 "+card.ID, card.ImagePath)
+	}
+	for _, noble := range nobles {
+		check("贵族 "+noble.ID, noble.ImagePath)
+	}
+	sort.Strings(missing)
+	c.problems = append(c.problems, missing...)
+}
+
+// 按卡牌颜色所在的轮盘行取出位置符号对应的颜色
+func wheelColor(cardColor models.GemType, symbol rune) models.GemType {
+	col := strings.IndexRune(wheelSymbols, symbol)
+	for _, row := range game.ColorWheel() {
+		if row[2] == cardColor {
+			return row[col]
+		}
+	}
+	return ""
+}
+
+func distinctBaseColors(colors []models.GemType) bool {
+	if len(colors) != len(baseColors) {
+		return false
+	}
+	seen := make(map[models.GemType]bool)
+	for _, color := range colors {
+		seen[color] = true
+	}
+	for _, color := range baseColors {
+		if !seen[color] {
+			return false
+		}
+	}
+	return true
+}
@@ -1,7 +1,9 @@
 package main
 
 import (
+	"flag"
 	"log"
+	"os"
 	"time"
 
 	"splendor-duel-backend/internal/game"
@@ -11,8 +13,51 @@ import (
 )
 
 func main() {
+	catalogPath := flag.String("catalog", "", "替代的卡牌目录文件（JSON），为空时使用内置目录")
+	debug := flag.Bool("debug", false, "调试模式：动作导致状态校验失败时冻结房间并导出现场")
+	dumpDir := flag.String("dump-dir", "dumps", "调试模式下导出现场的目录")
+	sessionSecret := flag.String("session-secret", os.Getenv("SESSION_SECRET"), "会话令牌签名密钥（默认读取 SESSION_SECRET），为空时使用随机密钥")
+	dataDir := flag.String("data-dir", "", "房间数据的保存目录，为空时只保存在内存中（重启后丢失）")
+	flag.Parse()
+
+	// 加载并校验卡牌目录
+	if *catalogPath != "" {
+		catalog, err := game.LoadCatalogFile(*catalogPath)
+		if err != nil {
+			log.Fatal("加载卡牌目录失败:", err)
+		}
+		game.SetCatalog(catalog)
+		log.Printf("使用卡牌目录: %s", *catalogPath)
+	} else {
+		catalog, err := game.ParseCatalog(game.DefaultCatalogJSON())
+		if err != nil {
+			log.Fatal("内置卡牌目录无效:", err)
+		}
+		game.SetCatalog(catalog)
+	}
+
 	// 创建游戏管理器
 	gameManager := game.NewManager()
+	if *sessionSecret != "" {
+		gameManager.SetSessionSecret([]byte(*sessionSecret))
+	} else {
+		log.Println("未设置会话密钥，使用随机密钥，服务重启后玩家需要重新加入房间")
+	}
+	if *dataDir != "" {
+		store, err := game.NewFileStore(*dataDir)
+		if err != nil {
+			log.Fatal("打开数据目录失败:", err)
+		}
+		gameManager.UseStore(store)
+		log.Printf("房间数据保存在: %s", *dataDir)
+		if *sessionSecret == "" {
+			log.Println("警告：未设置会话密钥，重启后恢复的房间无法用之前的令牌重新连接")
+		}
+	}
+	if *debug {
+		gameManager.SetDebug(true, *dumpDir)
+		log.Printf("调试模式已开启，现场导出目录: %s", *dumpDir)
+	}
 
 	// 启动房间清理协程（每24小时清理一次）
 	go func() {
@@ -45,9 +90,16 @@ func main() {
 	api := r.Group("/api")
 	{
 		// 房间管理
+		api.GET("/rulesets", gameManager.GetRuleSets)
+		api.GET("/catalog", gameManager.GetCatalog)
 		api.POST("/rooms", gameManager.CreateRoom)
 		api.POST("/rooms/join", gameManager.JoinRoom)
 		api.GET("/rooms/:roomId", gameManager.GetRoomInfo)
+		api.GET("/rooms/:roomId/session", gameManager.CheckSession)
+		api.GET("/rooms/:roomId/legal-actions", gameManager.GetLegalActions)
+		api.GET("/rooms/:roomId/payment-plans", gameManager.GetPaymentPlans)
+		api.GET("/rooms/:roomId/fairness", gameManager.GetGameRecord)
+		api.GET("/rooms/:roomId/record", gameManager.GetNotation)
 	}
 
 	// WebSocket 路由
@@ -55,6 +107,10 @@ func main() {
 		roomId := c.Param("roomId")
 		websocket.HandleWebSocket(c.Writer, c.Request, roomId, gameManager)
 	})
+	r.GET("/ws/:roomId/spectate", func(c *gin.Context) {
+		roomId := c.Param("roomId")
+		websocket.HandleSpectatorWebSocket(c.Writer, c.Request, roomId, gameManager)
+	})
 
 	// 启动服务器
 	log.Println("服务器启动在端口 8080...")
@@ -0,0 +1,113 @@
+// replay 读取文本棋谱并通过游戏逻辑重放整局对局，输出最终局面；任何一步被拒绝时以非零状态退出
+//
+//	go run ./cmd/replay [-catalog path/to/catalog.json] [-json] <棋谱文件|URL|->
+//
+// 棋谱可以来自 GET /api/rooms/:roomId/record，格式见 internal/game/notation.go
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"splendor-duel-backend/internal/game"
+	"splendor-duel-backend/internal/models"
+)
+
+func main() {
+	catalogPath := flag.String("catalog", "", "对局使用的卡牌目录文件（JSON），为空时使用内置目录")
+	asJSON := flag.Bool("json", false, "以 JSON 输出重放后的完整游戏状态")
+	flag.Parse()
+	if flag.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "用法: replay [-catalog path] [-json] <棋谱文件|URL|->")
+		os.Exit(2)
+	}
+
+	var (
+		catalog *game.Catalog
+		err     error
+	)
+	if *catalogPath != "" {
+		catalog, err = game.LoadCatalogFile(*catalogPath)
+	} else {
+		catalog, err = game.ParseCatalog(game.DefaultCatalogJSON())
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "目录加载失败:\n%v\n", err)
+		os.Exit(1)
+	}
+	game.SetCatalog(catalog)
+
+	text, err := readSource(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "读取棋谱失败: %v\n", err)
+		os.Exit(1)
+	}
+	notation, err := game.ParseNotation(text)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "解析棋谱失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	state, err := notation.Replay()
+	if err != nil {
+		fmt.Println("✗", err)
+		os.Exit(1)
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(state); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	printSummary(notation, state)
+}
+
+func printSummary(notation *game.Notation, state models.GameState) {
+	fmt.Printf("重放 %d 个动作，第 %d 回合，状态: %s\n", len(notation.Moves), state.TurnNumber, state.Status)
+	for _, player := range state.Players {
+		marker := " "
+		if player.ID == state.Winner {
+			marker = "★"
+		}
+		fmt.Printf("%s %s: %d 分，%d 皇冠，%d 张发展卡，%d 个贵族\n",
+			marker, player.Name, player.Points, player.Crowns, len(player.DevelopmentCards), len(player.Nobles))
+	}
+	if len(state.VictoryReasons) > 0 {
+		fmt.Printf("结束原因: %s\n", strings.Join(state.VictoryReasons, "，"))
+	}
+}
+
+// 从文件、URL 或标准输入（-）读取棋谱
+func readSource(source string) (string, error) {
+	var (
+		data []byte
+		err  error
+	)
+	switch {
+	case source == "-":
+		data, err = io.ReadAll(os.Stdin)
+	case strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://"):
+		var resp *http.Response
+		resp, err = http.Get(source)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		data, err = io.ReadAll(resp.Body)
+		if err == nil && resp.StatusCode != http.StatusOK {
+			return "", fmt.Errorf("服务端返回 %s: %s", resp.Status, strings.TrimSpace(string(data)))
+		}
+	default:
+		data, err = os.ReadFile(source)
+	}
+	return string(data), err
+}
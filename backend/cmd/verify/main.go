@@ -0,0 +1,114 @@
+// verify 校验一局已结束对局的随机结果：公开的种子与开局承诺相符，且由种子重放得到的
+// 起始玩家、初始宝石版图、牌堆顺序与每次补充版图都与记录一致，校验失败时以非零状态退出
+//
+//	go run ./cmd/verify [-catalog path/to/catalog.json] [-commitment <开局时看到的承诺>] <记录文件|URL|->
+//
+// 记录可以是 GET /api/rooms/:roomId/fairness 的响应，也可以直接是其中的 data 字段
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"splendor-duel-backend/internal/game"
+)
+
+func main() {
+	catalogPath := flag.String("catalog", "", "对局使用的卡牌目录文件（JSON），为空时使用内置目录")
+	commitment := flag.String("commitment", "", "开局时看到的种子承诺，为空时只校验记录自身")
+	flag.Parse()
+	if flag.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "用法: verify [-catalog path] [-commitment hash] <记录文件|URL|->")
+		os.Exit(2)
+	}
+
+	var (
+		catalog *game.Catalog
+		err     error
+	)
+	if *catalogPath != "" {
+		catalog, err = game.LoadCatalogFile(*catalogPath)
+	} else {
+		catalog, err = game.ParseCatalog(game.DefaultCatalogJSON())
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "目录加载失败:\n%v\n", err)
+		os.Exit(1)
+	}
+	game.SetCatalog(catalog)
+
+	record, err := loadRecord(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "读取记录失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *commitment != "" && !strings.EqualFold(*commitment, record.SeedCommitment) {
+		fmt.Printf("✗ 记录中的种子承诺 %s 与开局时看到的 %s 不符\n", record.SeedCommitment, *commitment)
+		os.Exit(1)
+	}
+
+	err = game.VerifyRecord(record)
+	if err != nil {
+		fmt.Println("✗", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("种子: %d\n", *record.Seed)
+	fmt.Printf("承诺: %s\n", record.SeedCommitment)
+	fmt.Printf("校验通过：重放 %d 个动作，起始玩家、宝石版图与翻开的发展卡均与记录一致\n", len(record.Steps))
+}
+
+// 从文件、URL 或标准输入（-）读取记录，兼容带 APIResponse 外壳的响应
+func loadRecord(source string) (game.GameRecord, error) {
+	var (
+		data []byte
+		err  error
+	)
+	switch {
+	case source == "-":
+		data, err = io.ReadAll(os.Stdin)
+	case strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://"):
+		data, err = fetch(source)
+	default:
+		data, err = os.ReadFile(source)
+	}
+	if err != nil {
+		return game.GameRecord{}, err
+	}
+
+	var envelope struct {
+		Success *bool           `json:"success"`
+		Message string          `json:"message"`
+		Data    json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return game.GameRecord{}, err
+	}
+	if envelope.Success != nil {
+		if !*envelope.Success {
+			return game.GameRecord{}, fmt.Errorf("服务端返回错误: %s", envelope.Message)
+		}
+		data = envelope.Data
+	}
+
+	var record game.GameRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return game.GameRecord{}, err
+	}
+	return record, nil
+}
+
+func fetch(url string) ([]byte, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
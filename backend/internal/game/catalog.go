@@ -0,0 +1,272 @@
+package game
+
+import (
+	"bytes"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"splendor-duel-backend/internal/models"
+)
+
+// 内置的官方卡牌目录
+//
+//go:embed catalog/default.json
+var defaultCatalogJSON []byte
+
+// WheelCardTemplate 普通卡模板：费用用轮盘公式描述，按5种颜色各生成一张（ID 为代号加序号，如 a1-a5）
+type WheelCardTemplate struct {
+	Code       string              `json:"code"`
+	Level      models.CardLevel    `json:"level"`
+	Formula    string              `json:"formula"` // 轮盘公式，如 "1Z 1X 1V 1B"
+	Points     int                 `json:"points"`
+	Crowns     int                 `json:"crowns"`
+	BonusCount int                 `json:"bonusCount"`
+	Effects    []models.CardEffect `json:"effects"`
+}
+
+// Catalog 发展卡与贵族的目录
+type Catalog struct {
+	WheelCards   []WheelCardTemplate   `json:"wheelCards"`
+	SpecialCards []DevelopmentCardData `json:"specialCards"` // 固定费用的特殊卡（百搭等）
+	Nobles       []models.NobleCard    `json:"nobles"`
+
+	cards []DevelopmentCardData // 展开后的全部发展卡
+}
+
+var (
+	catalogMutex   sync.RWMutex
+	currentCatalog *Catalog
+)
+
+// DefaultCatalogJSON 返回内置目录的原始内容
+func DefaultCatalogJSON() []byte {
+	return append([]byte(nil), defaultCatalogJSON...)
+}
+
+// DefaultCatalog 解析内置目录；内置目录无效属于程序错误
+func DefaultCatalog() *Catalog {
+	catalog, err := ParseCatalog(defaultCatalogJSON)
+	if err != nil {
+		panic(fmt.Sprintf("内置卡牌目录无效: %v", err))
+	}
+	return catalog
+}
+
+// CurrentCatalog 返回当前使用的卡牌目录，未设置时使用内置目录
+func CurrentCatalog() *Catalog {
+	catalogMutex.RLock()
+	catalog := currentCatalog
+	catalogMutex.RUnlock()
+	if catalog != nil {
+		return catalog
+	}
+
+	catalogMutex.Lock()
+	defer catalogMutex.Unlock()
+	if currentCatalog == nil {
+		currentCatalog = DefaultCatalog()
+	}
+	return currentCatalog
+}
+
+// SetCatalog 替换当前使用的卡牌目录（如扩展包或试玩用目录），只影响之后开始的对局：
+// 开局时卡牌与贵族数据已复制到游戏状态中（CardMap、NobleMap）
+func SetCatalog(catalog *Catalog) {
+	catalogMutex.Lock()
+	currentCatalog = catalog
+	catalogMutex.Unlock()
+}
+
+// LoadCatalogFile 从文件读取并校验卡牌目录
+func LoadCatalogFile(path string) (*Catalog, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取卡牌目录失败: %w", err)
+	}
+	catalog, err := ParseCatalog(data)
+	if err != nil {
+		return nil, fmt.Errorf("卡牌目录 %s 无效: %w", path, err)
+	}
+	return catalog, nil
+}
+
+// ParseCatalog 解析 JSON 目录，补全默认值并校验
+func ParseCatalog(data []byte) (*Catalog, error) {
+	var catalog Catalog
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&catalog); err != nil {
+		return nil, fmt.Errorf("解析失败: %w", err)
+	}
+	if err := catalog.build(); err != nil {
+		return nil, err
+	}
+	return &catalog, nil
+}
+
+// 展开轮盘模板、补全特殊卡的默认字段，然后校验
+func (c *Catalog) build() error {
+	cards, err := generateNormalCards(c.WheelCards)
+	if err != nil {
+		return err
+	}
+
+	for _, card := range c.SpecialCards {
+		if card.Code == "" {
+			card.Code = card.ID
+		}
+		if card.Color == "" {
+			card.Color = models.GemGray
+		}
+		if card.Bonus == "" {
+			card.Bonus = card.Color
+		}
+		if card.ImagePath == "" {
+			card.ImagePath = cardImagePath(card.ID)
+		}
+		card.IsSpecial = true
+		cards = append(cards, card)
+	}
+	c.cards = cards
+
+	for i := range c.Nobles {
+		if c.Nobles[i].ImagePath == "" {
+			c.Nobles[i].ImagePath = nobleImagePath(c.Nobles[i].ID)
+		}
+	}
+
+	return c.Validate()
+}
+
+// Validate 校验目录结构：ID 唯一、等级与颜色合法、费用为正、效果为引擎支持的效果
+func (c *Catalog) Validate() error {
+	var errs []error
+	fail := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	seen := make(map[string]bool)
+	for _, card := range c.cards {
+		if card.ID == "" {
+			fail("存在缺少 ID 的发展卡")
+			continue
+		}
+		if seen[card.ID] {
+			fail("发展卡 ID %s 重复", card.ID)
+		}
+		seen[card.ID] = true
+
+		if card.Level < models.Level1 || card.Level > models.Level3 {
+			fail("发展卡 %s 的等级 %d 无效", card.ID, card.Level)
+		}
+		if !isCardColor(card.Color) || !isCardColor(card.Bonus) {
+			fail("发展卡 %s 的颜色 %s/%s 无效", card.ID, card.Color, card.Bonus)
+		}
+		if card.Points < 0 || card.Crowns < 0 {
+			fail("发展卡 %s 的分数或皇冠为负数", card.ID)
+		}
+		if card.BonusCount < 1 {
+			fail("发展卡 %s 的 bonusCount 至少为 1", card.ID)
+		}
+		total := 0
+		for gem, count := range card.Cost {
+			if !isCostGem(gem) {
+				fail("发展卡 %s 的费用包含无效宝石 %s", card.ID, gem)
+			}
+			if count <= 0 {
+				fail("发展卡 %s 的费用 %s 必须为正数", card.ID, gem)
+			}
+			total += count
+		}
+		if total == 0 {
+			fail("发展卡 %s 没有费用", card.ID)
+		}
+		for _, effect := range card.Effects {
+			if !isKnownCardEffect(effect) {
+				fail("发展卡 %s 的效果 %s 不受支持", card.ID, effect)
+			}
+		}
+	}
+
+	nobles := make(map[string]bool)
+	for _, noble := range c.Nobles {
+		if noble.ID == "" {
+			fail("存在缺少 ID 的贵族")
+			continue
+		}
+		if nobles[noble.ID] {
+			fail("贵族 ID %s 重复", noble.ID)
+		}
+		nobles[noble.ID] = true
+		if noble.Points < 0 {
+			fail("贵族 %s 的分数为负数", noble.ID)
+		}
+		for _, effect := range noble.Effects {
+			if !isKnownNobleEffect(effect) {
+				fail("贵族 %s 的效果 %s 不受支持", noble.ID, effect)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// DevelopmentCards 返回目录中全部发展卡的副本
+func (c *Catalog) DevelopmentCards() []DevelopmentCardData {
+	return append([]DevelopmentCardData(nil), c.cards...)
+}
+
+// Version 目录内容的指纹（展开后的全部发展卡与贵族的 SHA-256 前12位），用于确认棋谱与目录一致
+func (c *Catalog) Version() string {
+	data, err := json.Marshal(struct {
+		Cards  []DevelopmentCardData `json:"cards"`
+		Nobles []models.NobleCard    `json:"nobles"`
+	}{c.cards, c.Nobles})
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// NobleIDs 按目录顺序返回全部贵族ID
+func (c *Catalog) NobleIDs() []string {
+	ids := make([]string, 0, len(c.Nobles))
+	for _, noble := range c.Nobles {
+		ids = append(ids, noble.ID)
+	}
+	return ids
+}
+
+// 卡面与贵族图片的默认路径（对应前端 public/images）
+func cardImagePath(cardID string) string {
+	return "/images/cards/" + cardID + ".jpg"
+}
+
+func nobleImagePath(nobleID string) string {
+	return "/images/nobles/" + nobleID + ".jpg"
+}
+
+// 发展卡颜色：5种一般颜色或灰色（百搭）
+func isCardColor(color models.GemType) bool {
+	switch color {
+	case models.GemWhite, models.GemBlue, models.GemGreen, models.GemRed, models.GemBlack, models.GemGray:
+		return true
+	}
+	return false
+}
+
+// 可以出现在费用中的宝石：5种一般颜色与珍珠
+func isCostGem(gem models.GemType) bool {
+	switch gem {
+	case models.GemWhite, models.GemBlue, models.GemGreen, models.GemRed, models.GemBlack, models.GemPearl:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,41 @@
+package game
+
+import "splendor-duel-backend/internal/models"
+
+// TakeGemsCmd 拿取宝石：1-3个位于同一直线上的版图位置
+type TakeGemsCmd struct {
+	Positions []models.Coord `json:"positions"`
+}
+
+// SpendPrivilegeCmd 花费特权指示物：每个位置花费一个特权
+type SpendPrivilegeCmd struct {
+	Positions []models.Coord `json:"positions"`
+}
+
+// ReserveCardCmd 保留发展卡：CardID 为场上卡牌，DeckLevel 非0时从该等级牌堆盲抽
+// 两者都为空时随机选择一个有剩余卡牌的等级（兼容旧版本）
+type ReserveCardCmd struct {
+	CardID    string           `json:"cardId,omitempty"`
+	DeckLevel models.CardLevel `json:"deckLevel,omitempty"`
+	Gold      models.Coord     `json:"gold"`
+}
+
+// BuyCardCmd 购买发展卡：Payment 为各类宝石（含黄金）的支付数量
+// AutoPay 为 true 时忽略 Payment，由服务端选择推荐的支付方案
+// Choices 为随购买一并提交的决策选择，用于预先回答购买后开启的决策
+type BuyCardCmd struct {
+	CardID  string                                        `json:"cardId"`
+	Payment map[models.GemType]int                        `json:"payment"`
+	AutoPay bool                                          `json:"autoPay,omitempty"`
+	Choices map[models.DecisionType]models.DecisionOption `json:"choices,omitempty"`
+}
+
+// DiscardGemsCmd 丢弃宝石：各类宝石的丢弃数量
+type DiscardGemsCmd struct {
+	Gems map[models.GemType]int `json:"gems"`
+}
+
+// ResolveDecisionCmd 回答当前待处理的决策
+type ResolveDecisionCmd struct {
+	Choice models.DecisionOption `json:"choice"`
+}
@@ -0,0 +1,286 @@
+package game
+
+import (
+	"fmt"
+	"splendor-duel-backend/internal/models"
+)
+
+// 贵族获取的皇冠阈值（第3与第6个皇冠）
+var nobleCrownThresholds = []int{3, 6}
+
+// 百搭卡可选择的颜色
+var wildcardColors = []models.GemType{models.GemWhite, models.GemBlue, models.GemGreen, models.GemRed, models.GemBlack}
+
+// 可被窃取的宝石类型（黄金除外）
+var stealableGems = []models.GemType{models.GemWhite, models.GemBlue, models.GemGreen, models.GemRed, models.GemBlack, models.GemPearl}
+
+// 计算给定皇冠数可获得的贵族总数
+func earnedNobleCount(crowns int) int {
+	count := 0
+	for _, threshold := range nobleCrownThresholds {
+		if crowns >= threshold {
+			count++
+		}
+	}
+	return count
+}
+
+// NobleCrownThreshold 返回玩家第 n 个贵族（从1开始）对应的皇冠阈值
+func NobleCrownThreshold(n int) int {
+	if n < 1 || n > len(nobleCrownThresholds) {
+		return 0
+	}
+	return nobleCrownThresholds[n-1]
+}
+
+// 购买发展卡后，按卡牌效果顺序触发效果（需要选择的效果开启决策），最后检查贵族
+func (gl *GameLogic) openCardDecisions(playerID string, card *models.DevelopmentCard) error {
+	if err := gl.triggerEffects(playerID, card.Effects, EffectSource{CardID: card.ID, Color: card.Color}); err != nil {
+		return err
+	}
+
+	// 皇冠跨过3/6时由服务端判定是否获得贵族
+	gl.checkNobleThresholds(playerID)
+	return nil
+}
+
+// 计算决策选项后加入队列；没有合法选项的决策不会开启。front 为 true 时插入队首
+func (gl *GameLogic) queueDecision(decision models.PendingDecision, front bool) bool {
+	decision.Options = gl.decisionOptions(decision)
+	if len(decision.Options) == 0 {
+		return false
+	}
+
+	if front {
+		gl.gameState.PendingDecisions = append([]models.PendingDecision{decision}, gl.gameState.PendingDecisions...)
+	} else {
+		gl.gameState.PendingDecisions = append(gl.gameState.PendingDecisions, decision)
+	}
+	return true
+}
+
+// 根据当前局面列出决策的全部合法选项：贵族决策由服务端列出可用贵族，其余由效果处理器列出
+func (gl *GameLogic) decisionOptions(decision models.PendingDecision) []models.DecisionOption {
+	if decision.Type == models.DecisionNoble {
+		var options []models.DecisionOption
+		for _, nobleID := range gl.gameState.AvailableNobles {
+			options = append(options, models.DecisionOption{NobleID: nobleID})
+		}
+		return options
+	}
+
+	handler, ok := decisionEffectHandler(decision)
+	if !ok {
+		return nil
+	}
+	return handler.Options(gl, decision)
+}
+
+// 局面变化后刷新队列中决策的选项，并移除已无合法选项的决策
+func (gl *GameLogic) refreshDecisions() {
+	var remaining []models.PendingDecision
+	for _, decision := range gl.gameState.PendingDecisions {
+		decision.Options = gl.decisionOptions(decision)
+		if len(decision.Options) > 0 {
+			remaining = append(remaining, decision)
+		}
+	}
+	gl.gameState.PendingDecisions = remaining
+}
+
+// 检查玩家皇冠是否跨过阈值且尚有未领取的贵族，若是则开启贵族决策
+func (gl *GameLogic) checkNobleThresholds(playerID string) bool {
+	player := gl.getPlayer(playerID)
+	if player == nil {
+		return false
+	}
+
+	for _, decision := range gl.gameState.PendingDecisions {
+		if decision.Type == models.DecisionNoble && decision.PlayerID == playerID {
+			return true
+		}
+	}
+
+	if earnedNobleCount(player.Crowns) <= len(player.Nobles) {
+		return false
+	}
+	return gl.queueDecision(models.PendingDecision{Type: models.DecisionNoble, PlayerID: playerID}, false)
+}
+
+// ResolveDecision 回答当前待处理的决策，cmd.Choice 必须是服务端列出的选项之一
+func (gl *GameLogic) ResolveDecision(playerID string, cmd ResolveDecisionCmd) error {
+	return gl.apply(GameAction{Type: ActionResolveDecision, PlayerID: playerID, ResolveDecision: &cmd})
+}
+
+func (gl *GameLogic) resolveDecision(playerID string, cmd ResolveDecisionCmd) error {
+	if gl.gameState.Status == models.GameStatusFinished {
+		return NewActionError(ErrGameFinished, "游戏已结束")
+	}
+
+	playerIndex := gl.getPlayerIndex(playerID)
+	if playerIndex == -1 {
+		return NewActionError(ErrPlayerNotFound, "玩家不存在")
+	}
+
+	if gl.gameState.CurrentPlayerIndex != playerIndex {
+		return NewActionError(ErrNotYourTurn, "不是该玩家的回合")
+	}
+
+	if err := gl.checkPhase(models.PhaseEffects); err != nil {
+		return err
+	}
+
+	if err := gl.applyDecision(playerID, cmd.Choice); err != nil {
+		return err
+	}
+
+	// 全部决策结算完毕后继续回合结束流程
+	if len(gl.gameState.PendingDecisions) > 0 {
+		return nil
+	}
+	return gl.HandleTurnEnd()
+}
+
+// 校验并结算队首决策
+func (gl *GameLogic) applyDecision(playerID string, choice models.DecisionOption) error {
+	if len(gl.gameState.PendingDecisions) == 0 {
+		return NewActionError(ErrNoPendingDecision, "当前没有待处理的决策")
+	}
+
+	decision := gl.gameState.PendingDecisions[0]
+	if decision.PlayerID != playerID {
+		return NewActionError(ErrNotYourTurn, "不是该玩家的决策")
+	}
+	if decision.Type == models.DecisionNoble {
+		if !containsOption(decision.Options, choice) {
+			return NewActionError(ErrInvalidChoice, "无效的选择")
+		}
+		gl.gameState.PendingDecisions = gl.gameState.PendingDecisions[1:]
+		if err := gl.handleNobleSelection(playerID, choice.NobleID); err != nil {
+			return err
+		}
+	} else {
+		handler, ok := decisionEffectHandler(decision)
+		if !ok {
+			return NewActionError(ErrInternal, "未注册的决策效果")
+		}
+		if err := handler.Validate(gl, decision, choice); err != nil {
+			return err
+		}
+		gl.gameState.PendingDecisions = gl.gameState.PendingDecisions[1:]
+		if err := handler.Apply(gl, decision, choice); err != nil {
+			return err
+		}
+	}
+
+	gl.refreshDecisions()
+	return nil
+}
+
+// 判断选择是否属于合法选项
+func containsOption(options []models.DecisionOption, choice models.DecisionOption) bool {
+	for _, option := range options {
+		if option.Gem != choice.Gem || option.NobleID != choice.NobleID {
+			continue
+		}
+		if option.Position == nil && choice.Position == nil {
+			return true
+		}
+		if option.Position != nil && choice.Position != nil && *option.Position == *choice.Position {
+			return true
+		}
+	}
+	return false
+}
+
+// 用随购买一并提交的选择依次回答队首决策，每种选择最多使用一次
+// 选择无效时返回错误，整个购买被拒绝
+func (gl *GameLogic) resolvePrepackedChoices(playerID string, prepacked map[models.DecisionType]models.DecisionOption) error {
+	choices := map[models.DecisionType]models.DecisionOption{}
+	for decisionType, choice := range prepacked {
+		choices[decisionType] = choice
+	}
+
+	for len(gl.gameState.PendingDecisions) > 0 {
+		decision := gl.gameState.PendingDecisions[0]
+		choice, ok := choices[decision.Type]
+		if !ok {
+			break
+		}
+		delete(choices, decision.Type)
+		if err := gl.applyDecision(playerID, choice); err != nil {
+			return NewActionError(ErrInvalidChoice, fmt.Sprintf("预先提交的选择无效（%s）: %v", decision.Type, err))
+		}
+	}
+
+	// 没有用到的选择（例如未获得贵族时附带的贵族选择）直接忽略
+	return nil
+}
+
+// 处理贵族选择与效果结算：分数与效果（窃取/新的回合/获取特权）来自卡牌目录
+func (gl *GameLogic) handleNobleSelection(playerID string, id string) error {
+	player := gl.getPlayer(playerID)
+	if player == nil {
+		return NewActionError(ErrPlayerNotFound, "玩家不存在")
+	}
+
+	noble, ok := gl.gameState.NobleMap[id]
+	if !ok {
+		return NewActionError(ErrInvalidChoice, "未知的贵族")
+	}
+	gl.emit(NobleGained{PlayerID: playerID, NobleID: id, Threshold: NobleCrownThreshold(len(player.Nobles) + 1)})
+
+	player.Points += noble.Points
+	if err := gl.triggerEffects(playerID, noble.Effects, EffectSource{NobleID: id}); err != nil {
+		return err
+	}
+
+	player.Nobles = append(player.Nobles, id)
+	// 从场上可用贵族中移除
+	var filtered []string
+	for _, nid := range gl.gameState.AvailableNobles {
+		if nid != id {
+			filtered = append(filtered, nid)
+		}
+	}
+	gl.gameState.AvailableNobles = filtered
+
+	// 一次跨过多个阈值时继续开启贵族决策
+	gl.checkNobleThresholds(playerID)
+	return nil
+}
+
+// 将百搭卡默认计入的灰色bonus转移到所选颜色，并同步卡牌详情
+func (gl *GameLogic) assignWildcardColor(player *models.Player, cardID string, chosen models.GemType) {
+	count := cardBonusCount(gl.gameState.CardMap[cardID])
+	player.Bonus[models.GemGray] -= count
+	if player.Bonus[models.GemGray] < 0 {
+		player.Bonus[models.GemGray] = 0
+	}
+	player.Bonus[chosen] += count
+
+	// 同步运行时卡牌详情映射，便于前端tooltip正确归类
+	if cd, ok := gl.gameState.CardDetails[cardID]; ok {
+		cd.Bonus = chosen
+		cd.Color = chosen
+		gl.gameState.CardDetails[cardID] = cd
+	}
+	if cm, ok := gl.gameState.CardMap[cardID]; ok {
+		cm.Bonus = chosen
+		cm.Color = chosen
+		gl.gameState.CardMap[cardID] = cm
+	}
+	// 移除灰色奖励显示
+	if player.Bonus[models.GemGray] == 0 {
+		delete(player.Bonus, models.GemGray)
+	}
+}
+
+// 获取对手
+func (gl *GameLogic) getOpponent(playerID string) *models.Player {
+	playerIndex := gl.getPlayerIndex(playerID)
+	if playerIndex == -1 || len(gl.gameState.Players) < 2 {
+		return nil
+	}
+	return &gl.gameState.Players[(playerIndex+1)%len(gl.gameState.Players)]
+}
@@ -0,0 +1,241 @@
+package game
+
+import (
+	"fmt"
+
+	"splendor-duel-backend/internal/models"
+)
+
+// EffectSource 效果来源：发展卡或贵族
+type EffectSource struct {
+	CardID  string
+	NobleID string
+	Color   models.GemType // 发展卡颜色，贵族为空
+}
+
+// EffectHandler 一次性效果的结算方式，按效果ID注册，发展卡与贵族共用
+// 需要玩家选择的效果由 Trigger 返回待决策（决策类型即效果ID），之后由 Options/Validate/Apply 结算
+type EffectHandler interface {
+	// Trigger 获得卡牌或贵族时调用；无需选择的效果直接结算并返回 nil
+	Trigger(gl *GameLogic, playerID string, source EffectSource) *models.PendingDecision
+	// Options 列出决策的全部合法选项，没有选项的决策不会开启
+	Options(gl *GameLogic, decision models.PendingDecision) []models.DecisionOption
+	// Validate 校验玩家对决策的选择
+	Validate(gl *GameLogic, decision models.PendingDecision, choice models.DecisionOption) error
+	// Apply 结算已通过校验的选择
+	Apply(gl *GameLogic, decision models.PendingDecision, choice models.DecisionOption) error
+}
+
+// 只能由发展卡触发的效果（依赖卡牌颜色或卡牌本身），贵族目录中不允许使用
+type cardOnlyEffect interface {
+	CardOnly() bool
+}
+
+// 已注册的效果
+var effectHandlers = map[models.CardEffect]EffectHandler{
+	models.ExtraToken:   extraTokenEffect{},
+	models.NewTurn:      newTurnEffect{},
+	models.Wildcard:     wildcardEffect{},
+	models.GetPrivilege: getPrivilegeEffect{},
+	models.Steal:        stealEffect{},
+}
+
+// RegisterEffect 注册（或替换）一种效果的结算方式，供变体规则与扩展使用
+func RegisterEffect(effect models.CardEffect, handler EffectHandler) {
+	effectHandlers[effect] = handler
+}
+
+// 判断效果是否可用于发展卡/贵族
+func isKnownCardEffect(effect models.CardEffect) bool {
+	_, ok := effectHandlers[effect]
+	return ok
+}
+
+func isKnownNobleEffect(effect models.CardEffect) bool {
+	handler, ok := effectHandlers[effect]
+	if !ok {
+		return false
+	}
+	if cardOnly, ok := handler.(cardOnlyEffect); ok && cardOnly.CardOnly() {
+		return false
+	}
+	return true
+}
+
+// 按顺序触发效果；贵族触发的决策排在队首，先于购买卡牌产生的决策结算
+// 遇到未注册的效果时返回错误，整个动作被拒绝
+func (gl *GameLogic) triggerEffects(playerID string, effects []models.CardEffect, source EffectSource) error {
+	for _, effect := range effects {
+		handler, ok := effectHandlers[effect]
+		if !ok {
+			return NewActionError(ErrInternal, fmt.Sprintf("未注册的效果: %s（卡牌: %s, 贵族: %s）", effect, source.CardID, source.NobleID))
+		}
+		if decision := handler.Trigger(gl, playerID, source); decision != nil {
+			gl.queueDecision(*decision, source.NobleID != "")
+		}
+	}
+	return nil
+}
+
+// 待决策对应的效果处理器
+func decisionEffectHandler(decision models.PendingDecision) (EffectHandler, bool) {
+	handler, ok := effectHandlers[models.CardEffect(decision.Type)]
+	return handler, ok
+}
+
+// 选择必须是服务端列出的选项之一
+func requireOption(decision models.PendingDecision, choice models.DecisionOption) error {
+	if !containsOption(decision.Options, choice) {
+		return NewActionError(ErrInvalidChoice, "无效的选择")
+	}
+	return nil
+}
+
+// 无需选择的效果：获得时直接结算，不会产生决策
+type immediateEffect struct{}
+
+func (immediateEffect) Options(*GameLogic, models.PendingDecision) []models.DecisionOption {
+	return nil
+}
+
+func (immediateEffect) Validate(*GameLogic, models.PendingDecision, models.DecisionOption) error {
+	return NewActionError(ErrInvalidChoice, "该效果无需选择")
+}
+
+func (immediateEffect) Apply(*GameLogic, models.PendingDecision, models.DecisionOption) error {
+	return nil
+}
+
+// 新的回合：获得一个额外回合
+type newTurnEffect struct{ immediateEffect }
+
+func (newTurnEffect) Trigger(gl *GameLogic, playerID string, source EffectSource) *models.PendingDecision {
+	if gl.gameState.ExtraTurns == nil {
+		gl.gameState.ExtraTurns = map[string]int{}
+	}
+	gl.gameState.ExtraTurns[playerID]++
+	gl.emit(ExtraTurnGranted{PlayerID: playerID, CardID: source.CardID, NobleID: source.NobleID})
+	return nil
+}
+
+// 获取特权：拿取一个特权指示物（统一使用拿取P函数）
+type getPrivilegeEffect struct{ immediateEffect }
+
+func (getPrivilegeEffect) Trigger(gl *GameLogic, playerID string, source EffectSource) *models.PendingDecision {
+	player := gl.getPlayer(playerID)
+	if player == nil {
+		return nil
+	}
+	before := player.PrivilegeTokens
+	_ = gl.TakePrivilegeToken(playerID)
+	if player.PrivilegeTokens > before {
+		gl.emit(PrivilegeGained{PlayerID: playerID, CardID: source.CardID, NobleID: source.NobleID})
+	}
+	return nil
+}
+
+// 额外token：从版图拿取一个与卡牌颜色相同的token
+type extraTokenEffect struct{}
+
+func (extraTokenEffect) CardOnly() bool { return true }
+
+func (extraTokenEffect) Trigger(gl *GameLogic, playerID string, source EffectSource) *models.PendingDecision {
+	return &models.PendingDecision{Type: models.DecisionExtraToken, PlayerID: playerID, CardID: source.CardID, Color: source.Color}
+}
+
+func (extraTokenEffect) Options(gl *GameLogic, decision models.PendingDecision) []models.DecisionOption {
+	var options []models.DecisionOption
+	for x, row := range gl.gameState.GemBoard {
+		for y, gem := range row {
+			if gem != "" && gem != models.GemGold && gem == decision.Color {
+				options = append(options, models.DecisionOption{Position: &models.Coord{X: x, Y: y}})
+			}
+		}
+	}
+	return options
+}
+
+func (extraTokenEffect) Validate(gl *GameLogic, decision models.PendingDecision, choice models.DecisionOption) error {
+	return requireOption(decision, choice)
+}
+
+func (extraTokenEffect) Apply(gl *GameLogic, decision models.PendingDecision, choice models.DecisionOption) error {
+	player := gl.getPlayer(decision.PlayerID)
+	pos := choice.Position
+	gem := gl.gameState.GemBoard[pos.X][pos.Y]
+	gl.gameState.GemBoard[pos.X][pos.Y] = ""
+	player.Gems[gem]++
+	gl.emit(GemsTaken{PlayerID: decision.PlayerID, Positions: []models.Coord{*pos}, Gems: []models.GemType{gem}, CardID: decision.CardID})
+	return nil
+}
+
+// 窃取：从对手处拿取一个非黄金token
+type stealEffect struct{}
+
+func (stealEffect) Trigger(gl *GameLogic, playerID string, source EffectSource) *models.PendingDecision {
+	return &models.PendingDecision{Type: models.DecisionSteal, PlayerID: playerID, CardID: source.CardID, NobleID: source.NobleID}
+}
+
+func (stealEffect) Options(gl *GameLogic, decision models.PendingDecision) []models.DecisionOption {
+	opponent := gl.getOpponent(decision.PlayerID)
+	if opponent == nil {
+		return nil
+	}
+	var options []models.DecisionOption
+	for _, gem := range stealableGems {
+		if opponent.Gems[gem] > 0 {
+			options = append(options, models.DecisionOption{Gem: gem})
+		}
+	}
+	return options
+}
+
+func (stealEffect) Validate(gl *GameLogic, decision models.PendingDecision, choice models.DecisionOption) error {
+	return requireOption(decision, choice)
+}
+
+func (stealEffect) Apply(gl *GameLogic, decision models.PendingDecision, choice models.DecisionOption) error {
+	player := gl.getPlayer(decision.PlayerID)
+	opponent := gl.getOpponent(decision.PlayerID)
+	opponent.Gems[choice.Gem]--
+	player.Gems[choice.Gem]++
+	gl.emit(TokenStolen{PlayerID: decision.PlayerID, FromPlayerID: opponent.ID, Gem: choice.Gem, CardID: decision.CardID, NobleID: decision.NobleID})
+	return nil
+}
+
+// 百搭颜色：为百搭卡选择计入的颜色
+type wildcardEffect struct{}
+
+func (wildcardEffect) CardOnly() bool { return true }
+
+func (wildcardEffect) Trigger(gl *GameLogic, playerID string, source EffectSource) *models.PendingDecision {
+	return &models.PendingDecision{Type: models.DecisionWildcard, PlayerID: playerID, CardID: source.CardID}
+}
+
+// 百搭卡只能计入玩家已拥有的发展卡颜色
+func (wildcardEffect) Options(gl *GameLogic, decision models.PendingDecision) []models.DecisionOption {
+	player := gl.getPlayer(decision.PlayerID)
+	if player == nil {
+		return nil
+	}
+	var options []models.DecisionOption
+	for _, color := range wildcardColors {
+		if player.Bonus[color] > 0 {
+			options = append(options, models.DecisionOption{Gem: color})
+		}
+	}
+	return options
+}
+
+func (wildcardEffect) Validate(gl *GameLogic, decision models.PendingDecision, choice models.DecisionOption) error {
+	if player := gl.getPlayer(decision.PlayerID); player == nil || player.Bonus[choice.Gem] <= 0 {
+		return NewActionError(ErrInvalidChoice, "百搭卡只能选择已拥有的发展卡颜色")
+	}
+	return requireOption(decision, choice)
+}
+
+func (wildcardEffect) Apply(gl *GameLogic, decision models.PendingDecision, choice models.DecisionOption) error {
+	gl.assignWildcardColor(gl.getPlayer(decision.PlayerID), decision.CardID, choice.Gem)
+	gl.emit(WildcardAssigned{PlayerID: decision.PlayerID, CardID: decision.CardID, Color: choice.Gem})
+	return nil
+}
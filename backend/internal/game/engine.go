@@ -0,0 +1,110 @@
+package game
+
+import "splendor-duel-backend/internal/models"
+
+// GameActionType 游戏行动类型
+type GameActionType string
+
+const (
+	ActionStartGame       GameActionType = "start_game"       // 开始游戏
+	ActionSpendPrivilege  GameActionType = "spend_privilege"  // 花费特权指示物
+	ActionRefillBoard     GameActionType = "refill_board"     // 补充版图
+	ActionTakeGems        GameActionType = "take_gems"        // 拿取宝石
+	ActionBuyCard         GameActionType = "buy_card"         // 购买发展卡
+	ActionReserveCard     GameActionType = "reserve_card"     // 保留发展卡
+	ActionDiscardGems     GameActionType = "discard_gems"     // 丢弃超出上限的宝石
+	ActionResolveDecision GameActionType = "resolve_decision" // 回答待处理的决策
+)
+
+// GameAction 游戏行动，根据 Type 只填写对应的命令
+type GameAction struct {
+	Type            GameActionType      `json:"type"`
+	PlayerID        string              `json:"playerId"`
+	TakeGems        *TakeGemsCmd        `json:"takeGems,omitempty"`
+	SpendPrivilege  *SpendPrivilegeCmd  `json:"spendPrivilege,omitempty"`
+	ReserveCard     *ReserveCardCmd     `json:"reserveCard,omitempty"`
+	BuyCard         *BuyCardCmd         `json:"buyCard,omitempty"`
+	DiscardGems     *DiscardGemsCmd     `json:"discardGems,omitempty"`
+	ResolveDecision *ResolveDecisionCmd `json:"resolveDecision,omitempty"`
+}
+
+// Apply 执行一个动作：不修改传入的状态，返回执行后的新状态与产生的领域事件
+// 动作被拒绝时返回原状态与错误
+func Apply(state models.GameState, action GameAction) (models.GameState, []Event, error) {
+	return ApplyWithRandom(state, action, nil)
+}
+
+// ApplyWithRandom 与 Apply 相同，但使用指定的随机数来源（为空时使用由游戏种子确定的来源）
+func ApplyWithRandom(state models.GameState, action GameAction, random RandomSource) (models.GameState, []Event, error) {
+	working := cloneGameState(&state)
+	engine := &GameLogic{gameState: &working, random: random}
+	if err := engine.dispatch(action); err != nil {
+		return state, nil, err
+	}
+	if state.Status != models.GameStatusFinished && working.Status == models.GameStatusFinished {
+		engine.emit(SeedRevealed{Seed: working.Seed, Commitment: working.SeedCommitment})
+	}
+
+	events := append([]Event{ActionApplied{Action: action}}, engine.events...)
+	return working, events, nil
+}
+
+// apply 通过 Apply 执行动作并提交到所持有的状态
+func (gl *GameLogic) apply(action GameAction) error {
+	next, events, err := ApplyWithRandom(*gl.gameState, action, gl.random)
+	if err != nil {
+		return err
+	}
+	*gl.gameState = next
+	gl.events = append(gl.events, events...)
+	return nil
+}
+
+// Events 返回通过该实例执行的动作产生的事件（按产生顺序）
+func (gl *GameLogic) Events() []Event {
+	return gl.events
+}
+
+// 按动作类型分派到具体的规则实现
+func (gl *GameLogic) dispatch(action GameAction) error {
+	missing := NewActionError(ErrInvalidAction, "缺少动作数据")
+
+	switch action.Type {
+	case ActionStartGame:
+		return gl.startGame()
+	case ActionTakeGems:
+		if action.TakeGems == nil {
+			return missing
+		}
+		return gl.takeGems(action.PlayerID, *action.TakeGems)
+	case ActionSpendPrivilege:
+		if action.SpendPrivilege == nil {
+			return missing
+		}
+		return gl.spendPrivilege(action.PlayerID, *action.SpendPrivilege)
+	case ActionRefillBoard:
+		return gl.refillBoard(action.PlayerID)
+	case ActionBuyCard:
+		if action.BuyCard == nil {
+			return missing
+		}
+		return gl.buyCard(action.PlayerID, *action.BuyCard)
+	case ActionReserveCard:
+		if action.ReserveCard == nil {
+			return missing
+		}
+		return gl.reserveCard(action.PlayerID, *action.ReserveCard)
+	case ActionDiscardGems:
+		if action.DiscardGems == nil {
+			return missing
+		}
+		return gl.discardGemsBatch(action.PlayerID, *action.DiscardGems)
+	case ActionResolveDecision:
+		if action.ResolveDecision == nil {
+			return missing
+		}
+		return gl.resolveDecision(action.PlayerID, *action.ResolveDecision)
+	default:
+		return NewActionError(ErrUnknownAction, "未知的游戏动作类型: "+string(action.Type))
+	}
+}
@@ -0,0 +1,281 @@
+package game
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+
+	"splendor-duel-backend/internal/models"
+)
+
+// 去掉与对局无关的时间字段，便于比较两次执行得到的状态
+func withoutTimes(state models.GameState) models.GameState {
+	state = cloneGameState(&state)
+	state.CreatedAt = time.Time{}
+	state.StartedAt = time.Time{}
+	for i := range state.Players {
+		state.Players[i].LastActive = time.Time{}
+	}
+	return state
+}
+
+// 用 Apply 从开局自动进行一局游戏，每一步之后调用 check
+func playWithApply(t *testing.T, seed int64, check func(previous, next models.GameState, action GameAction)) models.GameState {
+	t.Helper()
+	state, _, err := Apply(newTestState(seed), GameAction{Type: ActionStartGame})
+	if err != nil {
+		t.Fatalf("开始游戏失败: %v", err)
+	}
+	random := rand.New(rand.NewSource(seed))
+	for step := 0; step < 500 && state.Status == models.GameStatusPlaying; step++ {
+		action := nextTestAction(t, &state, random)
+		next, _, err := Apply(state, action)
+		if err != nil {
+			t.Fatalf("种子 %d 第 %d 步 %s 被拒绝: %v", seed, step+1, FormatMove(action), err)
+		}
+		check(state, next, action)
+		state = next
+	}
+	if state.Status != models.GameStatusFinished {
+		t.Fatalf("种子 %d 的对局在步数上限内没有结束", seed)
+	}
+	return state
+}
+
+func TestApplyDoesNotModifyInput(t *testing.T) {
+	for seed := int64(1); seed <= 5; seed++ {
+		playWithApply(t, seed, func(previous, _ models.GameState, action GameAction) {
+			before := marshalState(t, previous)
+			if _, _, err := Apply(previous, action); err != nil {
+				t.Fatal(err)
+			}
+			if marshalState(t, previous) != before {
+				t.Fatalf("种子 %d: Apply(%s) 修改了传入的状态", seed, FormatMove(action))
+			}
+		})
+	}
+}
+
+func TestApplyRejectedActionKeepsState(t *testing.T) {
+	state, _, err := Apply(newTestState(9), GameAction{Type: ActionStartGame})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waiting := state.Players[1-state.CurrentPlayerIndex].ID
+	before := marshalState(t, state)
+
+	action := takeGemsAction(t, &state)
+	action.PlayerID = waiting
+	next, events, err := Apply(state, action)
+	if CodeOf(err, "") != ErrNotYourTurn {
+		t.Fatalf("非当前玩家拿取宝石应被拒绝，实际 %v", err)
+	}
+	if events != nil || marshalState(t, next) != before || marshalState(t, state) != before {
+		t.Fatal("被拒绝的动作不应产生事件或修改状态")
+	}
+	if _, _, err := Apply(state, GameAction{Type: ActionStartGame}); CodeOf(err, "") != ErrGameNotStarted {
+		t.Fatalf("重复开始游戏应被拒绝，实际 %v", err)
+	}
+	if _, _, err := Apply(state, GameAction{Type: ActionTakeGems, PlayerID: waiting}); CodeOf(err, "") != ErrInvalidAction {
+		t.Fatalf("缺少动作数据应被拒绝，实际 %v", err)
+	}
+}
+
+func TestStartGameRequiresTwoPlayers(t *testing.T) {
+	state := newTestState(1)
+	state.Players = state.Players[:1]
+	if _, _, err := Apply(state, GameAction{Type: ActionStartGame}); CodeOf(err, "") != ErrGameNotStarted {
+		t.Fatalf("一名玩家不能开始游戏，实际 %v", err)
+	}
+}
+
+// 相同种子与相同动作得到完全相同的对局，不同种子得到不同的开局
+func TestSameSeedReplaysIdentically(t *testing.T) {
+	var actions []GameAction
+	final := playWithApply(t, 42, func(_, _ models.GameState, action GameAction) {
+		actions = append(actions, action)
+	})
+
+	state, _, err := Apply(newTestState(42), GameAction{Type: ActionStartGame})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, action := range actions {
+		if state, _, err = Apply(state, action); err != nil {
+			t.Fatalf("第 %d 步重放失败: %v", i+1, err)
+		}
+	}
+	if !reflect.DeepEqual(withoutTimes(state), withoutTimes(final)) {
+		t.Fatal("相同种子与动作重放得到的状态不同")
+	}
+
+	other, _, _ := Apply(newTestState(43), GameAction{Type: ActionStartGame})
+	first, _, _ := Apply(newTestState(42), GameAction{Type: ActionStartGame})
+	if reflect.DeepEqual(observe(&other), observe(&first)) {
+		t.Fatal("不同种子得到了相同的开局")
+	}
+}
+
+func TestInvariantsHoldThroughoutGames(t *testing.T) {
+	for seed := int64(1); seed <= 20; seed++ {
+		playWithApply(t, seed, func(_, next models.GameState, action GameAction) {
+			if err := NewGameLogic(&next).CheckInvariants(); err != nil {
+				t.Fatalf("种子 %d: %s 之后状态不一致:\n%v", seed, FormatMove(action), err)
+			}
+		})
+	}
+}
+
+// 有待处理的决策时只能由决策玩家回答合法选项，其他动作都被拒绝
+func TestPendingDecisionsBlockOtherActions(t *testing.T) {
+	decisions := 0
+	for seed := int64(1); seed <= 10; seed++ {
+		playWithApply(t, seed, func(_, next models.GameState, _ GameAction) {
+			if len(next.PendingDecisions) == 0 {
+				return
+			}
+			decisions++
+			decision := next.PendingDecisions[0]
+			before := marshalState(t, next)
+
+			other := next.Players[0].ID
+			if other == decision.PlayerID {
+				other = next.Players[1].ID
+			}
+			resolve := func(playerID string, choice models.DecisionOption) error {
+				_, _, err := Apply(next, GameAction{Type: ActionResolveDecision, PlayerID: playerID, ResolveDecision: &ResolveDecisionCmd{Choice: choice}})
+				return err
+			}
+			if err := resolve(other, decision.Options[0]); CodeOf(err, "") != ErrNotYourTurn {
+				t.Fatalf("对手不能回答 %s 决策，实际 %v", decision.Type, err)
+			}
+			if err := resolve(decision.PlayerID, models.DecisionOption{Gem: models.GemGold, NobleID: "no-such-noble"}); err == nil {
+				t.Fatalf("%s 决策接受了不在选项中的选择", decision.Type)
+			}
+			if _, _, err := Apply(next, GameAction{Type: ActionRefillBoard, PlayerID: decision.PlayerID}); err == nil {
+				t.Fatalf("%s 决策未回答时执行了补充版图", decision.Type)
+			}
+			if marshalState(t, next) != before {
+				t.Fatal("被拒绝的决策修改了状态")
+			}
+		})
+	}
+	if decisions == 0 {
+		t.Fatal("测试对局没有产生任何决策")
+	}
+}
+
+// 随购买一并提交的选择不合法时整个购买被拒绝，合法时直接回答对应的决策
+func TestBuyCardRejectsInvalidPrepackedChoice(t *testing.T) {
+	checked := 0
+	for seed := int64(1); seed <= 10; seed++ {
+		playWithApply(t, seed, func(previous, next models.GameState, action GameAction) {
+			if action.Type != ActionBuyCard || len(next.PendingDecisions) == 0 || next.PendingDecisions[0].Type == models.DecisionNoble {
+				return
+			}
+			checked++
+			decision := next.PendingDecisions[0]
+			buy := func(choice models.DecisionOption) (models.GameState, error) {
+				cmd := *action.BuyCard
+				cmd.Choices = map[models.DecisionType]models.DecisionOption{decision.Type: choice}
+				state, _, err := Apply(previous, GameAction{Type: ActionBuyCard, PlayerID: action.PlayerID, BuyCard: &cmd})
+				return state, err
+			}
+
+			if _, err := buy(models.DecisionOption{Gem: models.GemGold, NobleID: "no-such-noble"}); CodeOf(err, "") != ErrInvalidChoice {
+				t.Fatalf("种子 %d: 无效的 %s 预先选择应被拒绝，实际 %v", seed, decision.Type, err)
+			}
+			state, err := buy(decision.Options[0])
+			if err != nil {
+				t.Fatalf("种子 %d: 合法的 %s 预先选择被拒绝: %v", seed, decision.Type, err)
+			}
+			if len(state.PendingDecisions) > 0 && state.PendingDecisions[0].Type == decision.Type && state.PendingDecisions[0].PlayerID == decision.PlayerID {
+				t.Fatalf("种子 %d: 预先选择没有回答 %s 决策", seed, decision.Type)
+			}
+		})
+	}
+	if checked == 0 {
+		t.Fatal("测试对局没有购买带决策效果的卡牌")
+	}
+}
+
+// 去掉版图与袋子中的全部宝石、场上的发展卡和玩家的宝石，使当前玩家无法执行任何必选动作
+func stuckState(t *testing.T, rule string, bag []models.GemType) *GameLogic {
+	t.Helper()
+	state, _, err := Apply(newTestState(5), GameAction{Type: ActionStartGame})
+	if err != nil {
+		t.Fatal(err)
+	}
+	state.Rules.StalemateRule = rule
+	for x := range state.GemBoard {
+		for y := range state.GemBoard[x] {
+			state.GemBoard[x][y] = ""
+		}
+	}
+	state.GemBag = bag
+	for level := range state.FlippedCards {
+		state.FlippedCards[level] = []string{}
+	}
+	for i := range state.Players {
+		state.Players[i].Gems = make(map[models.GemType]int)
+	}
+	return NewGameLogic(&state)
+}
+
+func TestStalemateForcesRefillWhenBagHasGems(t *testing.T) {
+	gl := stuckState(t, models.StalematePass, []models.GemType{models.GemRed, models.GemBlue})
+	gl.ensureMandatoryAction()
+
+	state := gl.gameState
+	if state.Status != models.GameStatusPlaying || len(state.GemBag) != 0 {
+		t.Fatalf("应强制补充版图并继续游戏，状态 %s，袋中剩余 %d", state.Status, len(state.GemBag))
+	}
+	forced := false
+	for _, event := range gl.Events() {
+		if refill, ok := event.(BoardRefilled); ok && refill.Forced {
+			forced = true
+		}
+	}
+	if !forced {
+		t.Fatal("没有产生强制补充版图事件")
+	}
+}
+
+func TestStalemateEndsGameByScore(t *testing.T) {
+	for _, rule := range []string{models.StalematePass, models.StalemateScore} {
+		t.Run(rule, func(t *testing.T) {
+			gl := stuckState(t, rule, nil)
+			state := gl.gameState
+			state.Players[0].Points = 2
+			state.Players[1].Points = 5
+
+			gl.ensureMandatoryAction()
+			if state.Status != models.GameStatusFinished || state.Winner != state.Players[1].ID {
+				t.Fatalf("僵局应按分数结束并由高分玩家获胜，状态 %s，胜者 %q", state.Status, state.Winner)
+			}
+
+			passes := 0
+			for _, event := range gl.Events() {
+				if _, ok := event.(TurnPassed); ok {
+					passes++
+				}
+			}
+			want := 0
+			if rule == models.StalematePass {
+				want = len(state.Players)
+			}
+			if passes != want {
+				t.Fatalf("应跳过 %d 个回合，实际 %d", want, passes)
+			}
+		})
+	}
+}
+
+func TestStalemateDrawOnEqualScore(t *testing.T) {
+	gl := stuckState(t, models.StalemateScore, nil)
+	gl.ensureMandatoryAction()
+	if gl.gameState.Status != models.GameStatusFinished || gl.gameState.Winner != "" {
+		t.Fatalf("分数与皇冠相同应为平局，胜者 %q", gl.gameState.Winner)
+	}
+}
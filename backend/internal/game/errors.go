@@ -0,0 +1,60 @@
+package game
+
+import "errors"
+
+// ErrorCode 动作被拒绝的原因代码，供前端按代码处理
+type ErrorCode string
+
+const (
+	ErrInvalidAction          ErrorCode = "INVALID_ACTION"          // 动作数据格式错误
+	ErrUnknownAction          ErrorCode = "UNKNOWN_ACTION"          // 未知的动作类型
+	ErrRoomNotFound           ErrorCode = "ROOM_NOT_FOUND"          // 房间不存在
+	ErrGameNotStarted         ErrorCode = "GAME_NOT_STARTED"        // 游戏尚未开始或无法开始
+	ErrGameFinished           ErrorCode = "GAME_FINISHED"           // 游戏已结束
+	ErrUnauthorized           ErrorCode = "UNAUTHORIZED"            // 会话令牌无效
+	ErrPlayerNotFound         ErrorCode = "PLAYER_NOT_FOUND"        // 玩家不存在
+	ErrNotYourTurn            ErrorCode = "NOT_YOUR_TURN"           // 不是该玩家的回合
+	ErrWrongPhase             ErrorCode = "WRONG_PHASE"             // 当前回合阶段不允许该动作
+	ErrInvalidPosition        ErrorCode = "INVALID_POSITION"        // 版图位置超出范围
+	ErrEmptyCell              ErrorCode = "EMPTY_CELL"              // 该位置没有宝石
+	ErrGemsNotInLine          ErrorCode = "GEMS_NOT_IN_LINE"        // 宝石不在同一直线上或不相邻
+	ErrGoldNotTakeable        ErrorCode = "GOLD_NOT_TAKEABLE"       // 黄金只能通过保留发展卡获得
+	ErrInvalidGemCount        ErrorCode = "INVALID_GEM_COUNT"       // 宝石数量不合法
+	ErrInsufficientGems       ErrorCode = "INSUFFICIENT_GEMS"       // 宝石不足
+	ErrInsufficientPrivileges ErrorCode = "INSUFFICIENT_PRIVILEGES" // 特权指示物不足
+	ErrReserveFull            ErrorCode = "RESERVE_FULL"            // 保留区已满
+	ErrNoGold                 ErrorCode = "NO_GOLD"                 // 该位置没有黄金
+	ErrDeckEmpty              ErrorCode = "DECK_EMPTY"              // 牌堆已空
+	ErrBagEmpty               ErrorCode = "BAG_EMPTY"               // 宝石袋子为空
+	ErrCardNotFound           ErrorCode = "CARD_NOT_FOUND"          // 卡牌不存在或不可用
+	ErrNoDiscardNeeded        ErrorCode = "NO_DISCARD_NEEDED"       // 当前不需要丢弃宝石
+	ErrNoPendingDecision      ErrorCode = "NO_PENDING_DECISION"     // 当前没有待处理的决策
+	ErrInvalidChoice          ErrorCode = "INVALID_CHOICE"          // 决策选择不合法
+	ErrUndoUnavailable        ErrorCode = "UNDO_UNAVAILABLE"        // 无法悔棋（没有可撤销的动作或请求无效）
+	ErrRoomFrozen             ErrorCode = "ROOM_FROZEN"             // 调试模式下状态校验失败，房间已冻结
+	ErrInternal               ErrorCode = "INTERNAL_ERROR"          // 服务端内部错误
+)
+
+// ActionError 带原因代码的动作错误，Message 为展示给玩家的说明
+type ActionError struct {
+	Code    ErrorCode
+	Message string
+}
+
+func (e *ActionError) Error() string {
+	return e.Message
+}
+
+// NewActionError 创建带原因代码的动作错误
+func NewActionError(code ErrorCode, message string) *ActionError {
+	return &ActionError{Code: code, Message: message}
+}
+
+// CodeOf 返回错误的原因代码，非 ActionError 时返回 fallback
+func CodeOf(err error, fallback ErrorCode) ErrorCode {
+	var actionErr *ActionError
+	if errors.As(err, &actionErr) {
+		return actionErr.Code
+	}
+	return fallback
+}
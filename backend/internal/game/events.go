@@ -0,0 +1,155 @@
+package game
+
+import "splendor-duel-backend/internal/models"
+
+// Event 领域事件：动作执行过程中按发生顺序产生，供历史记录等订阅方使用
+type Event interface {
+	EventType() string
+}
+
+// ActionApplied 动作已成功执行（每个动作的第一个事件）
+type ActionApplied struct {
+	Action GameAction `json:"action"`
+}
+
+// GemsTaken 从版图拿取宝石；CardID 非空时为发展卡的额外token效果
+type GemsTaken struct {
+	PlayerID          string           `json:"playerId"`
+	Positions         []models.Coord   `json:"positions"`
+	Gems              []models.GemType `json:"gems"`
+	CardID            string           `json:"cardId,omitempty"`
+	OpponentPrivilege bool             `json:"opponentPrivilege,omitempty"` // 拿取3枚同色或2枚珍珠，对手获得特权
+}
+
+// PrivilegeSpent 花费特权指示物拿取宝石
+type PrivilegeSpent struct {
+	PlayerID  string           `json:"playerId"`
+	Positions []models.Coord   `json:"positions"`
+	Gems      []models.GemType `json:"gems"`
+}
+
+// PrivilegeGained 因卡牌或贵族效果获得特权指示物
+type PrivilegeGained struct {
+	PlayerID string `json:"playerId"`
+	CardID   string `json:"cardId,omitempty"`
+	NobleID  string `json:"nobleId,omitempty"`
+}
+
+// BoardRefilled 补充版图，对手获得特权
+type BoardRefilled struct {
+	PlayerID string `json:"playerId"`
+	Placed   int    `json:"placed"`           // 放回版图的宝石数量
+	Forced   bool   `json:"forced,omitempty"` // 没有必选动作时由服务端强制补充
+}
+
+// CardReserved 保留发展卡并获得黄金
+type CardReserved struct {
+	PlayerID string           `json:"playerId"`
+	CardID   string           `json:"cardId"`
+	Level    models.CardLevel `json:"level"`
+	FromDeck bool             `json:"fromDeck"` // 从牌堆盲抽
+	Gold     models.Coord     `json:"gold"`
+}
+
+// CardPurchased 购买发展卡
+type CardPurchased struct {
+	PlayerID    string                 `json:"playerId"`
+	CardID      string                 `json:"cardId"`
+	Level       models.CardLevel       `json:"level"`
+	Payment     map[models.GemType]int `json:"payment"`
+	FromReserve bool                   `json:"fromReserve"`
+}
+
+// TokenStolen 从对手处窃取一个token；CardID 或 NobleID 为效果来源
+type TokenStolen struct {
+	PlayerID     string         `json:"playerId"`
+	FromPlayerID string         `json:"fromPlayerId"`
+	Gem          models.GemType `json:"gem"`
+	CardID       string         `json:"cardId,omitempty"`
+	NobleID      string         `json:"nobleId,omitempty"`
+}
+
+// WildcardAssigned 为百搭卡选择颜色
+type WildcardAssigned struct {
+	PlayerID string         `json:"playerId"`
+	CardID   string         `json:"cardId"`
+	Color    models.GemType `json:"color"`
+}
+
+// NobleGained 皇冠达到阈值获得贵族
+type NobleGained struct {
+	PlayerID  string `json:"playerId"`
+	NobleID   string `json:"nobleId"`
+	Threshold int    `json:"threshold"`
+}
+
+// ExtraTurnGranted 因卡牌或贵族效果获得额外回合
+type ExtraTurnGranted struct {
+	PlayerID string `json:"playerId"`
+	CardID   string `json:"cardId,omitempty"`
+	NobleID  string `json:"nobleId,omitempty"`
+}
+
+// GemsDiscarded 丢弃超出上限的宝石
+type GemsDiscarded struct {
+	PlayerID string                 `json:"playerId"`
+	Gems     map[models.GemType]int `json:"gems"`
+}
+
+// GameWon 玩家达成胜利条件
+type GameWon struct {
+	PlayerID string   `json:"playerId"`
+	Reasons  []string `json:"reasons"`
+}
+
+// TurnPassed 玩家没有任何必选动作，回合被跳过
+type TurnPassed struct {
+	PlayerID string `json:"playerId"`
+}
+
+// GameDrawn 僵局按分数结束时双方分数与皇冠相同
+type GameDrawn struct {
+	Reasons []string `json:"reasons"`
+}
+
+// SeedCommitted 开局时公布本局随机种子的承诺，此后的洗牌与补充版图都由该种子决定
+type SeedCommitted struct {
+	Commitment string `json:"commitment"`
+}
+
+// SeedRevealed 游戏结束，公开随机种子，任何人都可以用 cmd/verify 校验对局的随机结果
+type SeedRevealed struct {
+	Seed       int64  `json:"seed"`
+	Commitment string `json:"commitment"`
+}
+
+// ActionUndone 经对手同意撤销了上一步动作，状态恢复到该动作执行之前（由房间管理器发布，不由动作产生）
+type ActionUndone struct {
+	PlayerID   string     `json:"playerId"`   // 请求悔棋的玩家
+	ApprovedBy string     `json:"approvedBy"` // 同意悔棋的玩家
+	Action     GameAction `json:"action"`     // 被撤销的动作
+}
+
+func (ActionApplied) EventType() string    { return "action_applied" }
+func (GemsTaken) EventType() string        { return "gems_taken" }
+func (PrivilegeSpent) EventType() string   { return "privilege_spent" }
+func (PrivilegeGained) EventType() string  { return "privilege_gained" }
+func (BoardRefilled) EventType() string    { return "board_refilled" }
+func (CardReserved) EventType() string     { return "card_reserved" }
+func (CardPurchased) EventType() string    { return "card_purchased" }
+func (TokenStolen) EventType() string      { return "token_stolen" }
+func (WildcardAssigned) EventType() string { return "wildcard_assigned" }
+func (NobleGained) EventType() string      { return "noble_gained" }
+func (ExtraTurnGranted) EventType() string { return "extra_turn_granted" }
+func (GemsDiscarded) EventType() string    { return "gems_discarded" }
+func (GameWon) EventType() string          { return "game_won" }
+func (TurnPassed) EventType() string       { return "turn_passed" }
+func (GameDrawn) EventType() string        { return "game_drawn" }
+func (SeedCommitted) EventType() string    { return "seed_committed" }
+func (SeedRevealed) EventType() string     { return "seed_revealed" }
+func (ActionUndone) EventType() string     { return "action_undone" }
+
+// 记录一个事件
+func (gl *GameLogic) emit(event Event) {
+	gl.events = append(gl.events, event)
+}
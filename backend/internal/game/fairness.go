@@ -0,0 +1,166 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+
+	"splendor-duel-backend/internal/models"
+)
+
+// GameRecord 一局游戏的公平性记录：开局前的状态、按顺序执行的动作，以及每个动作之后双方都能看到的结果
+// 游戏结束后公开种子，VerifyRecord 据此重放全部洗牌与补充版图，证明随机结果未被操纵
+type GameRecord struct {
+	SeedCommitment string           `json:"seedCommitment"` // 开局时公布的种子承诺
+	Seed           *int64           `json:"seed,omitempty"` // 随机种子，游戏结束后才公开
+	Initial        models.GameState `json:"initial"`        // 开始游戏前的状态（不含种子）
+	Steps          []RecordStep     `json:"steps"`          // 从开始游戏起按顺序执行的动作
+}
+
+// RecordStep 记录中的一个动作及其执行后的公开结果
+type RecordStep struct {
+	Action   GameAction    `json:"action"`
+	Observed PublicOutcome `json:"observed"`
+}
+
+// PublicOutcome 动作执行后双方都能看到、且取决于随机种子的信息：起始玩家、宝石版图与翻开的发展卡
+type PublicOutcome struct {
+	CurrentPlayerIndex int                           `json:"currentPlayerIndex"`
+	GemBoard           [][]models.GemType            `json:"gemBoard"`
+	FlippedCards       map[models.CardLevel][]string `json:"flippedCards"`
+}
+
+func observe(state *models.GameState) PublicOutcome {
+	clone := cloneGameState(state)
+	return PublicOutcome{
+		CurrentPlayerIndex: clone.CurrentPlayerIndex,
+		GemBoard:           clone.GemBoard,
+		FlippedCards:       clone.FlippedCards,
+	}
+}
+
+// 动作执行后追加到房间的公平性记录，开始游戏时新建记录（调用方持有 m.mutex）
+// 自动支付的购买按实际支付的宝石记录，重放与导出棋谱时不依赖推荐支付方案的顺序
+func (m *Manager) recordStep(roomID string, previous, next *models.GameState, action GameAction, events []Event) {
+	if action.BuyCard != nil && action.BuyCard.AutoPay {
+		for _, event := range events {
+			if purchase, ok := event.(CardPurchased); ok {
+				cmd := *action.BuyCard
+				cmd.AutoPay = false
+				cmd.Payment = purchase.Payment
+				action.BuyCard = &cmd
+			}
+		}
+	}
+
+	record := m.records[roomID]
+	if action.Type == ActionStartGame {
+		initial := cloneGameState(previous)
+		initial.Seed = 0
+		record = &GameRecord{Initial: initial}
+		m.records[roomID] = record
+	}
+	if record == nil {
+		return
+	}
+	record.Steps = append(record.Steps, RecordStep{Action: action, Observed: observe(next)})
+}
+
+// GameRecord 返回房间的公平性记录副本；种子只在游戏结束后包含在内
+func (m *Manager) GameRecord(roomID string) (GameRecord, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return m.gameRecordLocked(roomID)
+}
+
+// 公平性记录副本（调用方持有 m.mutex）
+func (m *Manager) gameRecordLocked(roomID string) (GameRecord, error) {
+	room, exists := m.rooms[roomID]
+	if !exists {
+		return GameRecord{}, NewActionError(ErrRoomNotFound, "房间不存在")
+	}
+	record, started := m.records[roomID]
+	if !started {
+		return GameRecord{}, NewActionError(ErrGameNotStarted, "游戏尚未开始")
+	}
+
+	result := GameRecord{
+		SeedCommitment: room.GameState.SeedCommitment,
+		Initial:        record.Initial,
+		Steps:          append([]RecordStep(nil), record.Steps...),
+	}
+	if room.GameState.Status == models.GameStatusFinished {
+		seed := room.GameState.Seed
+		result.Seed = &seed
+	}
+	return result, nil
+}
+
+// VerifyRecord 用公开的种子从开局前的状态重放记录中的每个动作：
+// 种子必须与承诺相符，每个动作都必须合法，且重放得到的起始玩家、宝石版图和翻开的发展卡与记录一致
+func VerifyRecord(record GameRecord) error {
+	if record.Seed == nil {
+		return errors.New("记录中没有公开的随机种子（游戏尚未结束）")
+	}
+	if commitment := SeedCommitment(*record.Seed); commitment != record.SeedCommitment {
+		return fmt.Errorf("种子 %d 的承诺为 %s，与开局公布的 %s 不符", *record.Seed, commitment, record.SeedCommitment)
+	}
+	if len(record.Steps) == 0 || record.Steps[0].Action.Type != ActionStartGame {
+		return errors.New("记录必须从开始游戏动作开始")
+	}
+
+	state := cloneGameState(&record.Initial)
+	state.Seed = *record.Seed
+	for i, step := range record.Steps {
+		next, _, err := Apply(state, step.Action)
+		if err != nil {
+			return fmt.Errorf("第 %d 步（%s）重放失败: %w", i+1, step.Action.Type, err)
+		}
+		if err := compareOutcome(observe(&next), step.Observed); err != nil {
+			return fmt.Errorf("第 %d 步（%s）之后%w", i+1, step.Action.Type, err)
+		}
+		if err := NewGameLogic(&next).CheckInvariants(); err != nil {
+			return fmt.Errorf("第 %d 步（%s）之后状态不一致:\n%w", i+1, step.Action.Type, err)
+		}
+		state = next
+	}
+
+	if state.SeedCommitment != record.SeedCommitment {
+		return fmt.Errorf("重放得到的种子承诺 %s 与记录不符", state.SeedCommitment)
+	}
+	if state.Status != models.GameStatusFinished {
+		return errors.New("重放全部动作后游戏仍未结束")
+	}
+	return nil
+}
+
+// 比较重放结果与记录中的公开结果，返回第一处不同
+func compareOutcome(replayed, recorded PublicOutcome) error {
+	if replayed.CurrentPlayerIndex != recorded.CurrentPlayerIndex {
+		return fmt.Errorf("当前玩家应为第 %d 位，记录为第 %d 位", replayed.CurrentPlayerIndex+1, recorded.CurrentPlayerIndex+1)
+	}
+	if len(replayed.GemBoard) != len(recorded.GemBoard) {
+		return fmt.Errorf("宝石版图应有 %d 行，记录为 %d 行", len(replayed.GemBoard), len(recorded.GemBoard))
+	}
+	for x := range replayed.GemBoard {
+		if len(replayed.GemBoard[x]) != len(recorded.GemBoard[x]) {
+			return fmt.Errorf("宝石版图第 %d 行应有 %d 格，记录为 %d 格", x, len(replayed.GemBoard[x]), len(recorded.GemBoard[x]))
+		}
+		for y, gem := range replayed.GemBoard[x] {
+			if recorded.GemBoard[x][y] != gem {
+				return fmt.Errorf("版图 (%d,%d) 应为 %q，记录为 %q", x, y, gem, recorded.GemBoard[x][y])
+			}
+		}
+	}
+	for level := models.Level1; level <= models.Level3; level++ {
+		want, got := replayed.FlippedCards[level], recorded.FlippedCards[level]
+		if len(want) != len(got) {
+			return fmt.Errorf("等级%d翻开的发展卡应为 %v，记录为 %v", level, want, got)
+		}
+		for i := range want {
+			if want[i] != got[i] {
+				return fmt.Errorf("等级%d翻开的发展卡应为 %v，记录为 %v", level, want, got)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package game
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"splendor-duel-backend/internal/models"
+)
+
+// 通过管理器完成一局游戏并返回其公平性记录（经过 JSON 往返，与 /fairness 接口返回的一致）
+func finishedRecord(t *testing.T, seed int64) GameRecord {
+	t.Helper()
+	m, roomID := newTestManager(seed)
+	playTestGame(t, m, roomID, seed)
+	record, err := m.GameRecord(roomID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded GameRecord
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	return decoded
+}
+
+func TestVerifyRecordAcceptsFinishedGames(t *testing.T) {
+	for seed := int64(1); seed <= 10; seed++ {
+		record := finishedRecord(t, seed)
+		if record.Seed == nil || *record.Seed != seed {
+			t.Fatalf("种子 %d: 结束后的记录应公开种子", seed)
+		}
+		if record.SeedCommitment != SeedCommitment(seed) {
+			t.Fatalf("种子 %d: 承诺为 %s", seed, record.SeedCommitment)
+		}
+		if record.Initial.Seed != 0 {
+			t.Fatalf("种子 %d: 开局前的状态不应包含种子", seed)
+		}
+		if err := VerifyRecord(record); err != nil {
+			t.Fatalf("种子 %d: %v", seed, err)
+		}
+	}
+}
+
+func TestVerifyRecordRejectsTampering(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(record *GameRecord)
+		want   string
+	}{
+		{"换成其他种子", func(r *GameRecord) { seed := *r.Seed + 1; r.Seed = &seed }, "承诺"},
+		{"换成其他种子与承诺", func(r *GameRecord) {
+			seed := *r.Seed + 1
+			r.Seed = &seed
+			r.SeedCommitment = SeedCommitment(seed)
+		}, "之后"},
+		{"修改起始玩家", func(r *GameRecord) {
+			r.Steps[0].Observed.CurrentPlayerIndex = 1 - r.Steps[0].Observed.CurrentPlayerIndex
+		}, "当前玩家"},
+		{"修改初始版图", func(r *GameRecord) {
+			board := r.Steps[0].Observed.GemBoard
+			board[0][0], board[4][4] = board[4][4], board[0][0]
+			if board[0][0] == board[4][4] {
+				board[0][0] = ""
+			}
+		}, "版图"},
+		{"修改翻开的发展卡", func(r *GameRecord) {
+			cards := r.Steps[len(r.Steps)/2].Observed.FlippedCards[models.Level1]
+			cards[0] = "tampered"
+		}, "发展卡"},
+		{"删除一个动作", func(r *GameRecord) { r.Steps = append(r.Steps[:3], r.Steps[4:]...) }, "第 4 步"},
+		{"缺少最后的动作", func(r *GameRecord) { r.Steps = r.Steps[:len(r.Steps)-1] }, "未结束"},
+		{"不从开始游戏开始", func(r *GameRecord) { r.Steps = r.Steps[1:] }, "开始游戏"},
+		{"未公开种子", func(r *GameRecord) { r.Seed = nil }, "种子"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := finishedRecord(t, 21)
+			tt.tamper(&record)
+			err := VerifyRecord(record)
+			if err == nil {
+				t.Fatal("被篡改的记录通过了校验")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("错误信息应包含 %q，实际 %v", tt.want, err)
+			}
+		})
+	}
+}
+
+// 游戏进行中的记录不公开种子，也无法校验
+func TestGameRecordHidesSeedUntilFinished(t *testing.T) {
+	m, roomID := newTestManager(8)
+	if _, err := m.GameRecord(roomID); CodeOf(err, "") != ErrGameNotStarted {
+		t.Fatalf("开始前不应有记录，实际 %v", err)
+	}
+	if _, err := m.ApplyAction(roomID, GameAction{Type: ActionStartGame}); err != nil {
+		t.Fatal(err)
+	}
+	record, err := m.GameRecord(roomID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Seed != nil || record.SeedCommitment != SeedCommitment(8) {
+		t.Fatalf("进行中的记录应只包含承诺，种子 %v，承诺 %s", record.Seed, record.SeedCommitment)
+	}
+	if err := VerifyRecord(record); err == nil {
+		t.Fatal("没有种子的记录通过了校验")
+	}
+}
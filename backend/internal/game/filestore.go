@@ -0,0 +1,239 @@
+package game
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"splendor-duel-backend/internal/models"
+)
+
+// FileStore 以 JSON 文件保存房间数据的 Store，每个房间一个目录：
+//
+//	<dir>/<roomID>/room.json     房间、游戏状态与公平性记录（整体覆盖写入）
+//	<dir>/<roomID>/history.jsonl 操作记录，每行一条
+//	<dir>/<roomID>/chat.jsonl    聊天消息，每行一条
+type FileStore struct {
+	dir   string
+	mutex sync.Mutex
+}
+
+// NewFileStore 在指定目录创建文件存储，目录不存在时自动创建
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// 房间目录；房间ID来自服务端生成的UUID，这里仍拒绝可能跳出存储目录的ID
+func (s *FileStore) roomDir(roomID string) (string, error) {
+	if roomID == "" || roomID == "." || roomID == ".." || strings.ContainsAny(roomID, `/\`) {
+		return "", fmt.Errorf("无效的房间ID: %q", roomID)
+	}
+	return filepath.Join(s.dir, roomID), nil
+}
+
+func (s *FileStore) LoadRooms() ([]StoredRoom, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		rooms []StoredRoom
+		errs  []error
+	)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(s.dir, entry.Name(), "room.json"))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		var room StoredRoom
+		if err := json.Unmarshal(data, &room); err != nil {
+			errs = append(errs, fmt.Errorf("房间 %s: %w", entry.Name(), err))
+			continue
+		}
+		rooms = append(rooms, room)
+	}
+	return rooms, errors.Join(errs...)
+}
+
+func (s *FileStore) SaveRoom(room StoredRoom) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	dir, err := s.roomDir(room.Room.ID)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(room)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(dir, "room.json"), data)
+}
+
+func (s *FileStore) DeleteRoom(roomID string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	dir, err := s.roomDir(roomID)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(dir)
+}
+
+func (s *FileStore) LoadHistory(roomID string) ([]models.GameAction, error) {
+	var history []models.GameAction
+	err := s.readLines(roomID, "history.jsonl", func(line []byte) error {
+		var entry models.GameAction
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return err
+		}
+		history = append(history, entry)
+		return nil
+	})
+	return history, err
+}
+
+func (s *FileStore) AppendHistory(roomID string, entry models.GameAction) error {
+	return s.appendLine(roomID, "history.jsonl", entry)
+}
+
+func (s *FileStore) TruncateHistory(roomID string, length int) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	dir, err := s.roomDir(roomID)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, "history.jsonl")
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	if length >= len(lines) {
+		return nil
+	}
+	return writeFileAtomic(path, bytes.Join(lines[:length], nil))
+}
+
+func (s *FileStore) LoadChat(roomID string) ([]models.ChatMessage, error) {
+	var chat []models.ChatMessage
+	err := s.readLines(roomID, "chat.jsonl", func(line []byte) error {
+		var message models.ChatMessage
+		if err := json.Unmarshal(line, &message); err != nil {
+			return err
+		}
+		chat = append(chat, message)
+		return nil
+	})
+	return chat, err
+}
+
+func (s *FileStore) AppendChat(roomID string, message models.ChatMessage) error {
+	return s.appendLine(roomID, "chat.jsonl", message)
+}
+
+// 追加一行 JSON；房间目录不存在（房间已删除）时忽略
+func (s *FileStore) appendLine(roomID, name string, value any) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	dir, err := s.roomDir(roomID)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(dir); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(filepath.Join(dir, name), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// 逐行读取 JSON 文件，文件不存在时视为空
+func (s *FileStore) readLines(roomID, name string, handle func(line []byte) error) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	dir, err := s.roomDir(roomID)
+	if err != nil {
+		return err
+	}
+	file, err := os.Open(filepath.Join(dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		if err := handle(scanner.Bytes()); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// 先写入临时文件再重命名，避免进程中途退出留下半个文件
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
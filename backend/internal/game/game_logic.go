@@ -1,58 +1,51 @@
 package game
 
 import (
-	"crypto/rand"
-	"errors"
 	"fmt"
-	"math/big"
 	"sort"
 	"splendor-duel-backend/internal/models"
-	"strconv"
 	"strings"
 )
 
-// GameActionType 游戏行动类型
-type GameActionType string
-
-const (
-	ActionSpendPrivilege    GameActionType = "spend_privilege"    // 花费特权指示物
-	ActionRefillBoard       GameActionType = "refill_board"       // 补充版图
-	ActionTakeGems          GameActionType = "take_gems"          // 拿取宝石
-	ActionBuyCard           GameActionType = "buy_card"           // 购买发展卡
-	ActionReserveCard       GameActionType = "reserve_card"       // 保留发展卡
-)
-
-// GameAction 游戏行动
-type GameAction struct {
-	Type      GameActionType              `json:"type"`
-	PlayerID  string                      `json:"playerId"`
-	Data      map[string]any      `json:"data"`
-}
-
-// GameLogic 游戏逻辑管理器
+// GameLogic 游戏逻辑：对外的方法是 Apply 的适配层，直接修改所持有的游戏状态
 type GameLogic struct {
 	gameState *models.GameState
-	manager   *Manager
+
+	// 通过该实例执行的动作产生的事件
+	events []Event
+
+	// 随机数来源，为空时使用由游戏种子确定的来源
+	random RandomSource
 }
 
 // NewGameLogic 创建新的游戏逻辑管理器
-func NewGameLogic(gameState *models.GameState, manager *Manager) *GameLogic {
+func NewGameLogic(gameState *models.GameState) *GameLogic {
 	return &GameLogic{
 		gameState: gameState,
-		manager:   manager,
 	}
 }
 
 // StartGame 开始游戏
 func (gl *GameLogic) StartGame() error {
+	return gl.apply(GameAction{Type: ActionStartGame})
+}
+
+func (gl *GameLogic) startGame() error {
 	if gl.gameState.Status != models.GameStatusWaiting {
-		return errors.New("游戏状态不正确，无法开始")
+		return NewActionError(ErrGameNotStarted, "游戏状态不正确，无法开始")
 	}
 	
 	// 随机决定起始玩家
 	if len(gl.gameState.Players) == 0 {
-		return errors.New("没有玩家，无法开始游戏")
+		return NewActionError(ErrGameNotStarted, "没有玩家，无法开始游戏")
+	}
+	if len(gl.gameState.Players) < 2 {
+		return NewActionError(ErrGameNotStarted, "玩家数量不足，无法开始游戏")
 	}
+	// 公布种子承诺：之后所有随机结果都由该种子决定，游戏结束时公开种子
+	gl.gameState.SeedCommitment = SeedCommitment(gl.gameState.Seed)
+	gl.emit(SeedCommitted{Commitment: gl.gameState.SeedCommitment})
+
 	gl.gameState.CurrentPlayerIndex = gl.getRandomInt(0, len(gl.gameState.Players)-1)
 	
 	// 后手玩家获得一个特权指示物（统一使用拿取P函数）
@@ -64,7 +57,8 @@ func (gl *GameLogic) StartGame() error {
 	// 初始化宝石版图
 	gl.initializeGemBoard()
 	
-	// 初始化发展卡
+	// 初始化发展卡（记录所用的卡牌目录版本）
+	gl.gameState.CatalogVersion = CurrentCatalog().Version()
 	gl.initializeDevelopmentCards()
 	
 	// 初始化贵族卡
@@ -77,32 +71,36 @@ func (gl *GameLogic) StartGame() error {
 	
 	// 初始化宝石丢弃相关字段
 	gl.gameState.NeedsGemDiscard = false
-	gl.gameState.GemDiscardTarget = 10
+	gl.gameState.GemDiscardTarget = gl.rules().MaxTokens
 	gl.gameState.GemDiscardPlayerID = ""
+
+	// 初始化待决策队列
+	gl.gameState.PendingDecisions = []models.PendingDecision{}
 	
 	// 设置游戏状态
 	gl.gameState.Status = models.GameStatusPlaying
 	gl.gameState.TurnNumber = 1
+	gl.gameState.TurnPhase = models.PhaseOptional
 	
 	return nil
 }
 
 // TakePrivilegeToken 统一的「拿取特权指示物（P）」函数
 // 规则：
-// 1) 若玩家已拥有3个P，则不变更，直接返回
+// 1) 若玩家已达到规则上限（官方规则为3个P），则不变更，直接返回
 // 2) 若公共区有剩余P，则从公共区减1，玩家加1
 // 3) 否则，从对手处转移1个P到该玩家（若对手有的话）
 func (gl *GameLogic) TakePrivilegeToken(playerID string) error {
     if gl.gameState.Status == models.GameStatusFinished {
-        return errors.New("游戏已结束")
+        return NewActionError(ErrGameFinished, "游戏已结束")
     }
     playerIndex := gl.getPlayerIndex(playerID)
     if playerIndex == -1 {
-        return errors.New("玩家不存在")
+        return NewActionError(ErrPlayerNotFound, "玩家不存在")
     }
 
-    // 最多3个
-    if gl.gameState.Players[playerIndex].PrivilegeTokens >= 3 {
+    // 不超过规则上限
+    if gl.gameState.Players[playerIndex].PrivilegeTokens >= gl.rules().MaxPrivileges {
         return nil
     }
 
@@ -129,7 +127,7 @@ func (gl *GameLogic) TakePrivilegeToken(playerID string) error {
 func (gl *GameLogic) GrantOpponentPrivilege(playerID string) error {
     playerIndex := gl.getPlayerIndex(playerID)
     if playerIndex == -1 {
-        return errors.New("玩家不存在")
+        return NewActionError(ErrPlayerNotFound, "玩家不存在")
     }
     if len(gl.gameState.Players) < 2 {
         return nil
@@ -138,6 +136,20 @@ func (gl *GameLogic) GrantOpponentPrivilege(playerID string) error {
     return gl.TakePrivilegeToken(gl.gameState.Players[opponentIndex].ID)
 }
 
+// TakeGemsGrantsPrivilege 判断一次拿取是否让对手获得特权：3枚同色（非黄金）或包含2枚珍珠
+func TakeGemsGrantsPrivilege(gems []models.GemType) bool {
+	if len(gems) == 3 && gems[0] == gems[1] && gems[1] == gems[2] && gems[0] != models.GemGold {
+		return true
+	}
+	pearls := 0
+	for _, g := range gems {
+		if g == models.GemPearl {
+			pearls++
+		}
+	}
+	return pearls >= 2
+}
+
 // 初始化宝石版图
 func (gl *GameLogic) initializeGemBoard() {
 	// 宝石版图坐标系统：5x5网格，从(0,0)到(4,4)
@@ -147,14 +159,11 @@ func (gl *GameLogic) initializeGemBoard() {
 	}
 	
 	// 创建宝石数组（正确数量：白蓝绿红黑各4个，珍珠2个，黄金3个，共25个）
-	gemTypes := []models.GemType{
-		models.GemWhite, models.GemWhite, models.GemWhite, models.GemWhite, // 4个白色
-		models.GemBlue, models.GemBlue, models.GemBlue, models.GemBlue,     // 4个蓝色
-		models.GemGreen, models.GemGreen, models.GemGreen, models.GemGreen, // 4个绿色
-		models.GemRed, models.GemRed, models.GemRed, models.GemRed,         // 4个红色
-		models.GemBlack, models.GemBlack, models.GemBlack, models.GemBlack, // 4个黑色
-		models.GemPearl, models.GemPearl,                                  // 2个珍珠
-		models.GemGold, models.GemGold, models.GemGold,                    // 3个黄金
+	var gemTypes []models.GemType
+	for _, supply := range gemSupply {
+		for i := 0; i < supply.Count; i++ {
+			gemTypes = append(gemTypes, supply.Gem)
+		}
 	}
 	
 	// 随机打乱宝石顺序
@@ -188,8 +197,9 @@ func (gl *GameLogic) initializeGemBoard() {
 
 // 初始化发展卡
 func (gl *GameLogic) initializeDevelopmentCards() {
-	// 获取所有发展卡
+	// 获取所有发展卡，按ID排序保证相同种子洗出相同的牌堆
 	allCards := GetAllDevelopmentCards()
+	sort.Slice(allCards, func(i, j int) bool { return allCards[i].ID < allCards[j].ID })
 	
 	// 初始化卡牌详细信息映射和快速查找映射
 	gl.gameState.CardDetails = make(map[string]models.DevelopmentCard)
@@ -200,16 +210,18 @@ func (gl *GameLogic) initializeDevelopmentCards() {
 	for _, card := range allCards {
 		// 将DevelopmentCardData转换为models.DevelopmentCard并存储
 		devCard := models.DevelopmentCard{
-			ID:        card.ID,
-			Level:     card.Level,
-			Code:      card.Code,
-			Color:     card.Color,
-			Points:    card.Points,
-			Crowns:    card.Crowns,
-			Bonus:     card.Bonus,
-			Cost:      card.Cost,
-			Effects:   card.Effects,
-			IsSpecial: card.IsSpecial,
+			ID:         card.ID,
+			Level:      card.Level,
+			Code:       card.Code,
+			Color:      card.Color,
+			Points:     card.Points,
+			Crowns:     card.Crowns,
+			Bonus:      card.Bonus,
+			Cost:       card.Cost,
+			Effects:    card.Effects,
+			IsSpecial:  card.IsSpecial,
+			BonusCount: card.BonusCount,
+			ImagePath:  card.ImagePath,
 		}
 		gl.gameState.CardDetails[card.ID] = devCard
 		gl.gameState.CardMap[card.ID] = devCard
@@ -254,36 +266,33 @@ func (gl *GameLogic) initializeDevelopmentCards() {
 	
 	// 从牌堆顶翻开初始卡牌
 	gl.gameState.FlippedCards = map[models.CardLevel][]string{
-		models.Level1: gl.drawCardsFromDeck(models.Level1, 5),
-		models.Level2: gl.drawCardsFromDeck(models.Level2, 4),
-		models.Level3: gl.drawCardsFromDeck(models.Level3, 3),
+		models.Level1: gl.drawCardsFromDeck(models.Level1, gl.faceUpCount(models.Level1)),
+		models.Level2: gl.drawCardsFromDeck(models.Level2, gl.faceUpCount(models.Level2)),
+		models.Level3: gl.drawCardsFromDeck(models.Level3, gl.faceUpCount(models.Level3)),
 	}
 }
 
 // 初始化贵族卡
 func (gl *GameLogic) initializeNobleCards() {
-	// 设置可用的贵族卡（4张固定贵族）
-	gl.gameState.AvailableNobles = []string{
-		"noble1", // 2分&窃取
-		"noble2", // 2分&新的回合
-		"noble3", // 2分&获取特权
-		"noble4", // 3分
+	// 设置可用的贵族卡并复制贵族数据，之后替换卡牌目录不影响进行中的对局
+	catalog := CurrentCatalog()
+	gl.gameState.AvailableNobles = catalog.NobleIDs()
+	gl.gameState.NobleMap = make(map[string]models.NobleCard, len(catalog.Nobles))
+	for _, noble := range catalog.Nobles {
+		gl.gameState.NobleMap[noble.ID] = noble
 	}
 }
 
-// 获取随机整数
+// 获取随机整数（闭区间 [min, max]）
 func (gl *GameLogic) getRandomInt(min, max int) int {
 	if max < min {
 		return min
 	}
-	n := int64(max - min + 1)
+	n := max - min + 1
 	if n <= 0 {
 		return min
 	}
-	if rb, err := rand.Int(rand.Reader, big.NewInt(n)); err == nil {
-		return min + int(rb.Int64())
-	}
-	return min
+	return min + gl.randomSource().Intn(n)
 }
 
 // 洗乱牌堆
@@ -323,50 +332,57 @@ func (gl *GameLogic) drawCardsFromDeck(level models.CardLevel, count int) []stri
 	return drawnCards
 }
 
-// 验证宝石是否在一条直线上且连续
-func (gl *GameLogic) validateGemLine(positions []any) bool {
-	// 少于2个位置，视为有效（前端已限制最多3个）
+// 校验一次拿取的宝石：1-3个位置均在版图内且不重复，每个位置都有非黄金宝石，
+// 并且沿横/竖/斜方向连续排列，中间没有空位（黄金只能通过保留发展卡获得）
+func (gl *GameLogic) validateGemLine(positions []models.Coord) error {
 	n := len(positions)
-	if n < 2 {
-		return true
+	if n < 1 || n > 3 {
+		return NewActionError(ErrInvalidGemCount, "只能拿取1-3个宝石")
 	}
 
-	// 解析为整型坐标切片，忽略传入顺序
-	type pt struct{ x, y int }
-	pts := make([]pt, 0, n)
-	for _, p := range positions {
-		m, ok := p.(map[string]any)
-		if !ok {
-			return false
+	seen := make(map[models.Coord]bool, n)
+	for _, pos := range positions {
+		if pos.X < 0 || pos.X >= len(gl.gameState.GemBoard) || pos.Y < 0 || pos.Y >= len(gl.gameState.GemBoard[pos.X]) {
+			return NewActionError(ErrInvalidPosition, "宝石位置超出范围")
 		}
-		xf, okx := m["x"].(float64)
-		yf, oky := m["y"].(float64)
-		if !okx || !oky {
-			return false
+		if seen[pos] {
+			return NewActionError(ErrInvalidPosition, "不能重复选择同一位置")
 		}
-		pts = append(pts, pt{ x: int(xf), y: int(yf) })
-	}
+		seen[pos] = true
 
-	// 判定是否同一条线（横/竖/两条对角线）
-	sameRow, sameCol, diag1, diag2 := true, true, true, true
-	for i := 1; i < n; i++ {
-		if pts[i].x != pts[0].x { sameCol = false }
-		if pts[i].y != pts[0].y { sameRow = false }
-		if (pts[i].x-pts[i].y) != (pts[0].x-pts[0].y) { diag1 = false }
-		if (pts[i].x+pts[i].y) != (pts[0].x+pts[0].y) { diag2 = false }
+		switch gl.gameState.GemBoard[pos.X][pos.Y] {
+		case "":
+			return NewActionError(ErrEmptyCell, "该位置没有宝石")
+		case models.GemGold:
+			return NewActionError(ErrGoldNotTakeable, "黄金只能通过保留发展卡获得")
+		}
 	}
-	if !(sameRow || sameCol || diag1 || diag2) {
-		return false
+	if n == 1 {
+		return nil
 	}
 
-	// 检查是否连续
-	sort.Slice(pts, func(i, j int) bool {
-		return pts[i].x < pts[j].x || (pts[i].x == pts[j].x && pts[i].y < pts[j].y)
+	// 按行列排序后，相邻两个位置之间的步长必须相同且为单位方向，即连续且没有空隙
+	sorted := append([]models.Coord(nil), positions...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].X < sorted[j].X || (sorted[i].X == sorted[j].X && sorted[i].Y < sorted[j].Y)
 	})
-	if n == 2 {
-		return pts[1].x - pts[0].x <= 1 && pts[1].y - pts[0].y <= 1
+	step := models.Coord{X: sorted[1].X - sorted[0].X, Y: sorted[1].Y - sorted[0].Y}
+	isDirection := false
+	for _, dir := range lineDirections {
+		if step == dir {
+			isDirection = true
+			break
+		}
 	}
-	return pts[1].x * 2 == pts[0].x + pts[2].x && pts[1].y * 2 == pts[0].y + pts[2].y
+	if !isDirection {
+		return NewActionError(ErrGemsNotInLine, "宝石不在同一直线上或不相邻")
+	}
+	for i := 2; i < n; i++ {
+		if sorted[i].X-sorted[i-1].X != step.X || sorted[i].Y-sorted[i-1].Y != step.Y {
+			return NewActionError(ErrGemsNotInLine, "宝石不在同一直线上或不相邻")
+		}
+	}
+	return nil
 }
 
 // 计算应支付费用
@@ -399,42 +415,9 @@ func (gl *GameLogic) removeCardFromBoard(cardID string) (models.CardLevel, int)
 	return 0, -1
 }
 
-// 结算卡牌效果
-func (gl *GameLogic) resolveCardEffects(card *DevelopmentCardData, playerID string) {
-	player := gl.getPlayer(playerID)
-	if player == nil {
-		return
-	}
-	
-	for _, effect := range card.Effects {
-		switch effect {
-		case models.ExtraToken:
-			// 额外token效果，待实现
-		case models.NewTurn:
-			// 新回合效果
-			gl.gameState.ExtraTurns[playerID]++
-		case models.Wildcard:
-			// 百搭颜色效果，待实现
-		case models.GetPrivilege:
-			// 获取特权效果（统一使用拿取P函数）
-			_ = gl.TakePrivilegeToken(playerID)
-		case models.Steal:
-			// 窃取效果，待实现
-		}
-	}
-}
-
 // 统一的补充发展卡函数
 func (gl *GameLogic) refillDevelopmentCards(level models.CardLevel, removedCardIndex int) {
-	var targetCount int
-	switch level {
-	case models.Level2:
-		targetCount = 4
-	case models.Level3:
-		targetCount = 3
-	default:
-		targetCount = 5
-	}
+	targetCount := gl.faceUpCount(level)
 	
 	currentCount := len(gl.gameState.FlippedCards[level])
 	
@@ -459,15 +442,19 @@ func (gl *GameLogic) refillDevelopmentCards(level models.CardLevel, removedCardI
 // 检查胜利条件，返回是否胜利以及原因列表
 func (gl *GameLogic) checkVictoryForPlayer(p *models.Player) (bool, []string) {
 	reasons := []string{}
-	// 条件1：总分达到20
-	if p.Points >= 20 {
-		reasons = append(reasons, "总分达到 20 分")
+	rules := DefaultRuleSet()
+	if gl != nil && gl.gameState != nil {
+		rules = gl.rules()
+	}
+	// 条件1：总分达到阈值（官方规则20）
+	if p.Points >= rules.VictoryPoints {
+		reasons = append(reasons, fmt.Sprintf("总分达到 %d 分", rules.VictoryPoints))
 	}
-	// 条件2：皇冠达到10
-	if p.Crowns >= 10 {
-		reasons = append(reasons, "皇冠数达到 10 个")
+	// 条件2：皇冠达到阈值（官方规则10）
+	if p.Crowns >= rules.VictoryCrowns {
+		reasons = append(reasons, fmt.Sprintf("皇冠数达到 %d 个", rules.VictoryCrowns))
 	}
-	// 条件3：任一颜色的发展卡总分达到10（白/蓝/绿/红/黑），含百搭改色
+	// 条件3：任一颜色的发展卡总分达到阈值（官方规则10，白/蓝/绿/红/黑），含百搭改色
 	if gl != nil && gl.gameState != nil {
 		colorPoints := map[models.GemType]int{
 			models.GemWhite: 0,
@@ -484,17 +471,17 @@ func (gl *GameLogic) checkVictoryForPlayer(p *models.Player) (bool, []string) {
 				}
 			}
 		}
-		// 检查是否有任一颜色 >= 10（可能不止一个）
+		// 检查是否有任一颜色达到阈值（可能不止一个）
 		for color, pts := range colorPoints {
-			if pts >= 10 {
+			if pts >= rules.VictoryColorPoints {
 				cn := map[models.GemType]string{
-					models.GemWhite: "白色发展卡总分达到 10 分",
-					models.GemBlue:  "蓝色发展卡总分达到 10 分",
-					models.GemGreen: "绿色发展卡总分达到 10 分",
-					models.GemRed:   "红色发展卡总分达到 10 分",
-					models.GemBlack: "黑色发展卡总分达到 10 分",
+					models.GemWhite: "白色",
+					models.GemBlue:  "蓝色",
+					models.GemGreen: "绿色",
+					models.GemRed:   "红色",
+					models.GemBlack: "黑色",
 				}[color]
-				reasons = append(reasons, cn)
+				reasons = append(reasons, fmt.Sprintf("%s发展卡总分达到 %d 分", cn, rules.VictoryColorPoints))
 			}
 		}
 	}
@@ -518,30 +505,33 @@ func (gl *GameLogic) HandleTurnEnd() error {
 		}
 	}
 	
+	// 等待玩家完成全部决策（窃取等效果可能改变宝石数量，需先于丢弃检查）
+	if len(gl.gameState.PendingDecisions) > 0 {
+		gl.gameState.TurnPhase = models.PhaseEffects
+		return nil
+	}
+	
 	// 检查当前玩家宝石数量是否超过限制
 	currentPlayer := &gl.gameState.Players[gl.gameState.CurrentPlayerIndex]
 	totalGems := gl.calculateTotalGems(currentPlayer)
+	maxTokens := gl.rules().MaxTokens
 	
-	// 添加调试日志
-	fmt.Printf("回合结束检查 - 玩家ID: %s, 宝石总数: %d, 需要丢弃: %v\n", 
-		currentPlayer.ID, totalGems, totalGems > 10)
-	
-	if totalGems > 10 {
+	if totalGems > maxTokens {
 		// 设置需要丢弃宝石的状态，并记录需要丢弃的玩家ID
 		gl.gameState.NeedsGemDiscard = true
-		gl.gameState.GemDiscardTarget = 10
+		gl.gameState.GemDiscardTarget = maxTokens
 		gl.gameState.GemDiscardPlayerID = currentPlayer.ID
-		fmt.Printf("设置宝石丢弃状态 - 玩家ID: %s, NeedsGemDiscard: %v, Target: %d\n", 
-			currentPlayer.ID, gl.gameState.NeedsGemDiscard, gl.gameState.GemDiscardTarget)
+		gl.gameState.TurnPhase = models.PhaseDiscard
 		return nil // 不切换回合，等待玩家丢弃宝石
 	}
 	
 	// 检查胜利条件
 	if won, reasons := gl.checkVictoryForPlayer(currentPlayer); won {
 		gl.gameState.Status = models.GameStatusFinished
+		gl.gameState.TurnPhase = models.PhaseEnd
 		gl.gameState.Winner = currentPlayer.ID
 		gl.gameState.VictoryReasons = reasons
-		fmt.Printf("游戏结束，胜者: %s，原因: %v\n", currentPlayer.Name, reasons)
+		gl.emit(GameWon{PlayerID: currentPlayer.ID, Reasons: reasons})
 		return nil
 	}
 	
@@ -549,6 +539,9 @@ func (gl *GameLogic) HandleTurnEnd() error {
 	gl.gameState.RefilledThisTurn = false
 	// 切换到下一个玩家
 	gl.nextTurn()
+
+	// 新回合没有必选动作时强制补充版图或按规则处理僵局
+	gl.ensureMandatoryAction()
 	
 	return nil
 }
@@ -556,95 +549,46 @@ func (gl *GameLogic) HandleTurnEnd() error {
 // 计算玩家总宝石数量
 func (gl *GameLogic) calculateTotalGems(player *models.Player) int {
 	total := 0
-	fmt.Printf("计算玩家 %s 的宝石总数:\n", player.ID)
 	for gemType, count := range player.Gems {
 		if gemType != "" { // 排除空字符串
-			fmt.Printf("  %s: %d\n", gemType, count)
 			total += count
 		}
 	}
-	fmt.Printf("总宝石数: %d\n", total)
 	return total
 }
 
-// 丢弃宝石
+// DiscardGem 丢弃一个宝石
 func (gl *GameLogic) DiscardGem(playerID string, gemType models.GemType) error {
-	if gl.gameState.Status == models.GameStatusFinished {
-		return errors.New("游戏已结束")
-	}
-
-	fmt.Printf("DiscardGem 被调用 - 玩家ID: %s, 宝石类型: %s\n", playerID, gemType)
-	
-	playerIndex := gl.getPlayerIndex(playerID)
-	if playerIndex == -1 {
-		return errors.New("玩家不存在")
-	}
-	
-	// 检查是否为当前玩家
-	if gl.gameState.CurrentPlayerIndex != playerIndex {
-		return errors.New("不是该玩家的回合")
-	}
-	
-	// 检查是否真的需要丢弃宝石
-	if !gl.gameState.NeedsGemDiscard {
-		fmt.Printf("当前不需要丢弃宝石 - NeedsGemDiscard: %v\n", gl.gameState.NeedsGemDiscard)
-		return errors.New("当前不需要丢弃宝石")
-	}
-	
-	player := &gl.gameState.Players[playerIndex]
-	
-	// 检查玩家是否有该类型的宝石
-	if player.Gems[gemType] <= 0 {
-		return errors.New("没有该类型的宝石可以丢弃")
-	}
-	
-	// 丢弃一个宝石
-	player.Gems[gemType]--
-	fmt.Printf("丢弃宝石成功 - 类型: %s, 剩余: %d\n", gemType, player.Gems[gemType])
-	
-	// 将宝石放回袋子
-	gl.gameState.GemBag = append(gl.gameState.GemBag, gemType)
-	
-	// 检查是否已经达到目标数量
-	totalGems := gl.calculateTotalGems(player)
-	fmt.Printf("丢弃后宝石总数: %d, 目标: %d\n", totalGems, gl.gameState.GemDiscardTarget)
-	
-	if totalGems <= gl.gameState.GemDiscardTarget {
-		// 重置丢弃状态
-		gl.gameState.NeedsGemDiscard = false
-		gl.gameState.GemDiscardTarget = 10
-		gl.gameState.GemDiscardPlayerID = ""
-		fmt.Printf("达到目标数量，重置状态\n")
-		
-		// 不自动切换回合，等待前端确认
-		// 前端确认后会调用 handleTurnEnd 来切换回合
-	}
-	
-	return nil
+	return gl.DiscardGemsBatch(playerID, DiscardGemsCmd{Gems: map[models.GemType]int{gemType: 1}})
 }
 
 // DiscardGemsBatch 批量丢弃宝石
-func (gl *GameLogic) DiscardGemsBatch(playerID string, gemDiscards map[models.GemType]int) error {
+func (gl *GameLogic) DiscardGemsBatch(playerID string, cmd DiscardGemsCmd) error {
+	return gl.apply(GameAction{Type: ActionDiscardGems, PlayerID: playerID, DiscardGems: &cmd})
+}
+
+func (gl *GameLogic) discardGemsBatch(playerID string, cmd DiscardGemsCmd) error {
+	gemDiscards := cmd.Gems
 	if gl.gameState.Status == models.GameStatusFinished {
-		return errors.New("游戏已结束")
+		return NewActionError(ErrGameFinished, "游戏已结束")
 	}
-
-	fmt.Printf("DiscardGemsBatch 被调用 - 玩家ID: %s, 丢弃详情: %v\n", playerID, gemDiscards)
 	
 	playerIndex := gl.getPlayerIndex(playerID)
 	if playerIndex == -1 {
-		return errors.New("玩家不存在")
+		return NewActionError(ErrPlayerNotFound, "玩家不存在")
 	}
 	
 	// 检查是否为当前玩家
 	if gl.gameState.CurrentPlayerIndex != playerIndex {
-		return errors.New("不是该玩家的回合")
+		return NewActionError(ErrNotYourTurn, "不是该玩家的回合")
 	}
 	
 	// 检查是否真的需要丢弃宝石
-	if !gl.gameState.NeedsGemDiscard {
-		fmt.Printf("当前不需要丢弃宝石 - NeedsGemDiscard: %v\n", gl.gameState.NeedsGemDiscard)
-		return errors.New("当前不需要丢弃宝石")
+	if !gl.gameState.NeedsGemDiscard || gl.gameState.GemDiscardPlayerID != playerID {
+		return NewActionError(ErrNoDiscardNeeded, "当前不需要丢弃宝石")
+	}
+	if err := gl.checkPhase(models.PhaseDiscard); err != nil {
+		return err
 	}
 	
 	player := &gl.gameState.Players[playerIndex]
@@ -657,7 +601,7 @@ func (gl *GameLogic) DiscardGemsBatch(playerID string, gemDiscards map[models.Ge
 		
 		// 检查玩家是否有足够的该类型宝石
 		if player.Gems[gemType] < count {
-			return fmt.Errorf("没有足够的 %s 宝石，需要 %d，实际有 %d", gemType, count, player.Gems[gemType])
+			return NewActionError(ErrInsufficientGems, fmt.Sprintf("没有足够的 %s 宝石，需要 %d，实际有 %d", gemType, count, player.Gems[gemType]))
 		}
 	}
 	
@@ -669,28 +613,29 @@ func (gl *GameLogic) DiscardGemsBatch(playerID string, gemDiscards map[models.Ge
 		
 		// 丢弃宝石
 		player.Gems[gemType] -= count
-		
-		// 将宝石放回袋子
-		for i := 0; i < count; i++ {
-			gl.gameState.GemBag = append(gl.gameState.GemBag, gemType)
+	}
+	
+	discarded := make(map[models.GemType]int)
+	for gemType, count := range gemDiscards {
+		if count > 0 {
+			discarded[gemType] = count
 		}
-		
-		fmt.Printf("批量丢弃宝石 - 类型: %s, 数量: %d, 剩余: %d\n", gemType, count, player.Gems[gemType])
 	}
+	// 将宝石放回袋子
+	gl.returnGemsToBag(discarded)
+	gl.emit(GemsDiscarded{PlayerID: playerID, Gems: discarded})
 	
 	// 检查是否已经达到目标数量
 	totalGems := gl.calculateTotalGems(player)
-	fmt.Printf("批量丢弃后宝石总数: %d, 目标: %d\n", totalGems, gl.gameState.GemDiscardTarget)
 	
 	if totalGems <= gl.gameState.GemDiscardTarget {
 		// 重置丢弃状态
 		gl.gameState.NeedsGemDiscard = false
-		gl.gameState.GemDiscardTarget = 10
+		gl.gameState.GemDiscardTarget = gl.rules().MaxTokens
 		gl.gameState.GemDiscardPlayerID = ""
-		fmt.Printf("达到目标数量，重置状态\n")
 		
-		// 不自动切换回合，等待前端确认
-		// 前端确认后会调用 handleTurnEnd 来切换回合
+		// 丢弃完成后由服务端继续回合结束流程（胜利检查与切换回合）
+		return gl.HandleTurnEnd()
 	}
 	
 	return nil
@@ -710,16 +655,18 @@ func (gl *GameLogic) drawCardFromDeck(level models.CardLevel) *DevelopmentCardDa
 		if card, exists := gl.gameState.CardMap[cardID]; exists {
 			// 将 models.DevelopmentCard 转换为 DevelopmentCardData
 			return &DevelopmentCardData{
-				ID:        card.ID,
-				Level:     card.Level,
-				Code:      card.Code,
-				Color:     card.Color,
-				Points:    card.Points,
-				Crowns:    card.Crowns,
-				Bonus:     card.Bonus,
-				Cost:      card.Cost,
-				Effects:   card.Effects,
-				IsSpecial: card.IsSpecial,
+				ID:         card.ID,
+				Level:      card.Level,
+				Code:       card.Code,
+				Color:      card.Color,
+				Points:     card.Points,
+				Crowns:     card.Crowns,
+				Bonus:      card.Bonus,
+				Cost:       card.Cost,
+				Effects:    card.Effects,
+				IsSpecial:  card.IsSpecial,
+				BonusCount: card.BonusCount,
+				ImagePath:  card.ImagePath,
 			}
 		}
 	}
@@ -729,24 +676,19 @@ func (gl *GameLogic) drawCardFromDeck(level models.CardLevel) *DevelopmentCardDa
 
 // 切换到下一个玩家
 func (gl *GameLogic) nextTurn() {
-	fmt.Printf("nextTurn 被调用 - 当前玩家索引: %d\n", gl.gameState.CurrentPlayerIndex)
-	
 	// 检查是否有额外回合
 	currentPlayer := gl.gameState.Players[gl.gameState.CurrentPlayerIndex]
 	if gl.gameState.ExtraTurns[currentPlayer.ID] > 0 {
 		gl.gameState.ExtraTurns[currentPlayer.ID]--
-		fmt.Printf("玩家 %s 有额外回合，继续当前玩家回合\n", currentPlayer.ID)
 		// 继续当前玩家的回合
+		gl.gameState.TurnPhase = models.PhaseOptional
 		return
 	}
 	
 	// 切换到下一个玩家
-	oldIndex := gl.gameState.CurrentPlayerIndex
 	gl.gameState.CurrentPlayerIndex = (gl.gameState.CurrentPlayerIndex + 1) % len(gl.gameState.Players)
 	gl.gameState.TurnNumber++
-	
-	fmt.Printf("回合切换 - 从玩家 %d 切换到玩家 %d, 回合数: %d\n", 
-		oldIndex, gl.gameState.CurrentPlayerIndex, gl.gameState.TurnNumber)
+	gl.gameState.TurnPhase = models.PhaseOptional
 }
 
 
@@ -771,63 +713,81 @@ func (gl *GameLogic) getPlayer(playerID string) *models.Player {
 	return nil
 }
 
+// 校验当前回合阶段是否允许执行该动作
+func (gl *GameLogic) checkPhase(allowed ...models.TurnPhase) error {
+	phase := gl.gameState.TurnPhase
+	for _, p := range allowed {
+		if p == phase {
+			return nil
+		}
+	}
+
+	switch phase {
+	case models.PhaseOptional:
+		return NewActionError(ErrWrongPhase, "本回合尚未执行必选动作")
+	case models.PhaseMandatory:
+		return NewActionError(ErrWrongPhase, "本回合已补充版图，只能执行必选动作")
+	case models.PhaseEffects:
+		return NewActionError(ErrWrongPhase, "请先完成效果结算")
+	case models.PhaseDiscard:
+		return NewActionError(ErrWrongPhase, "请先丢弃多余的宝石")
+	case models.PhaseEnd:
+		return NewActionError(ErrWrongPhase, "本回合已结束")
+	default:
+		return NewActionError(ErrGameNotStarted, "游戏尚未开始")
+	}
+}
+
 // TakeGems 拿取宝石
-func (gl *GameLogic) TakeGems(playerID string, gemPositions []map[string]any) error {
+func (gl *GameLogic) TakeGems(playerID string, cmd TakeGemsCmd) error {
+	return gl.apply(GameAction{Type: ActionTakeGems, PlayerID: playerID, TakeGems: &cmd})
+}
+
+func (gl *GameLogic) takeGems(playerID string, cmd TakeGemsCmd) error {
 	if gl.gameState.Status == models.GameStatusFinished {
-		return errors.New("游戏已结束")
+		return NewActionError(ErrGameFinished, "游戏已结束")
 	}
 	playerIndex := gl.getPlayerIndex(playerID)
 	if playerIndex == -1 {
-		return errors.New("玩家不存在")
+		return NewActionError(ErrPlayerNotFound, "玩家不存在")
 	}
 	
 	if gl.gameState.CurrentPlayerIndex != playerIndex {
-		return errors.New("不是该玩家的回合")
-	}
-	
-	if len(gemPositions) < 1 || len(gemPositions) > 3 {
-		return errors.New("只能拿取1-3个宝石")
+		return NewActionError(ErrNotYourTurn, "不是该玩家的回合")
 	}
-	
-	// 验证宝石位置和连续性
-	// 转换类型以匹配validateGemLine函数的参数
-	var positions []any
-	for _, pos := range gemPositions {
-		positions = append(positions, pos)
+
+	if err := gl.checkPhase(models.PhaseOptional, models.PhaseMandatory); err != nil {
+		return err
 	}
 	
-	if !gl.validateGemLine(positions) {
-		return errors.New("宝石不在同一直线上或不相邻")
+	// 验证宝石数量、位置、黄金和连续性
+	if err := gl.validateGemLine(cmd.Positions); err != nil {
+		return err
 	}
 	
 	// 从版图上移除宝石并添加到玩家手中
-	for _, pos := range gemPositions {
-		x, xOk := pos["x"].(float64)
-		y, yOk := pos["y"].(float64)
-		if !xOk || !yOk {
-			return errors.New("无效的宝石位置")
-		}
-		
-		rowIndex, colIndex := int(x), int(y)
-		if rowIndex < 0 || rowIndex >= 5 || colIndex < 0 || colIndex >= 5 {
-			return errors.New("宝石位置超出范围")
-		}
-		
+	var takenGems []models.GemType
+	for _, pos := range cmd.Positions {
+		rowIndex, colIndex := pos.X, pos.Y
 		gemType := gl.gameState.GemBoard[rowIndex][colIndex]
-		if gemType == "" {
-			return errors.New("该位置没有宝石")
-		}
 		
 		// 将宝石添加到玩家手中
 		gl.gameState.Players[playerIndex].Gems[gemType]++
+		takenGems = append(takenGems, gemType)
 		
 			// 从版图上移除宝石
 	gl.gameState.GemBoard[rowIndex][colIndex] = ""
 	}
+
+	// 拿取3枚同色或2枚珍珠时，对手获得一个特权指示物
+	grantsPrivilege := TakeGemsGrantsPrivilege(takenGems)
+	if grantsPrivilege {
+		_ = gl.GrantOpponentPrivilege(playerID)
+	}
+	gl.emit(GemsTaken{PlayerID: playerID, Positions: cmd.Positions, Gems: takenGems, OpponentPrivilege: grantsPrivilege})
 	
 	// 调用回合结束处理函数，检查宝石数量
 	if err := gl.HandleTurnEnd(); err != nil {
-		fmt.Printf("回合结束处理失败: %v\n", err)
 		return err
 	}
 	
@@ -835,32 +795,46 @@ func (gl *GameLogic) TakeGems(playerID string, gemPositions []map[string]any) er
 }
 
 // ReserveCard 保留发展卡
-func (gl *GameLogic) ReserveCard(playerID string, cardID string, goldX, goldY int) error {
+func (gl *GameLogic) ReserveCard(playerID string, cmd ReserveCardCmd) error {
+	return gl.apply(GameAction{Type: ActionReserveCard, PlayerID: playerID, ReserveCard: &cmd})
+}
+
+func (gl *GameLogic) reserveCard(playerID string, cmd ReserveCardCmd) error {
 	if gl.gameState.Status == models.GameStatusFinished {
-		return errors.New("游戏已结束")
+		return NewActionError(ErrGameFinished, "游戏已结束")
 	}
 
 	playerIndex := gl.getPlayerIndex(playerID)
 	if playerIndex == -1 {
-		return errors.New("玩家不存在")
+		return NewActionError(ErrPlayerNotFound, "玩家不存在")
 	}
 	
 	if gl.gameState.CurrentPlayerIndex != playerIndex {
-		return errors.New("不是该玩家的回合")
+		return NewActionError(ErrNotYourTurn, "不是该玩家的回合")
+	}
+
+	if err := gl.checkPhase(models.PhaseOptional, models.PhaseMandatory); err != nil {
+		return err
+	}
+	
+	// 必须指定场上翻开的卡牌或要盲抽的牌堆等级
+	if cmd.CardID == "" && cmd.DeckLevel == 0 {
+		return NewActionError(ErrInvalidAction, "缺少卡牌ID或牌堆等级")
 	}
 	
 	// 验证黄金位置
+	goldX, goldY := cmd.Gold.X, cmd.Gold.Y
 	if goldX < 0 || goldX >= 5 || goldY < 0 || goldY >= 5 {
-		return errors.New("黄金位置超出范围")
+		return NewActionError(ErrInvalidPosition, "黄金位置超出范围")
 	}
 	
 	if gl.gameState.GemBoard[goldX][goldY] != "gold" {
-		return errors.New("该位置没有黄金")
+		return NewActionError(ErrNoGold, "该位置没有黄金")
 	}
 	
 	// 检查玩家保留区是否已满
-	if len(gl.gameState.Players[playerIndex].ReservedCards) >= 3 {
-		return errors.New("保留区已满，无法保留更多卡牌")
+	if len(gl.gameState.Players[playerIndex].ReservedCards) >= gl.rules().MaxReserved {
+		return NewActionError(ErrReserveFull, "保留区已满，无法保留更多卡牌")
 	}
 	
 	// 将黄金添加到玩家手中
@@ -871,53 +845,18 @@ func (gl *GameLogic) ReserveCard(playerID string, cardID string, goldX, goldY in
 	
 	var reservedCardID string
 	
-	if strings.HasPrefix(cardID, "deck_level_") {
+	cardID := cmd.CardID
+	if cmd.DeckLevel != 0 {
 		// 从牌堆盲抽卡牌
-		// 解析等级信息
-		levelStr := strings.TrimPrefix(cardID, "deck_level_")
-		levelInt, err := strconv.Atoi(levelStr)
-		if err != nil {
-			return errors.New("无效的牌堆等级信息")
-		}
-		
-		selectedLevel := models.CardLevel(levelInt)
+		selectedLevel := cmd.DeckLevel
 		if selectedLevel < 1 || selectedLevel > 3 {
-			return errors.New("无效的牌堆等级")
+			return NewActionError(ErrInvalidAction, "无效的牌堆等级")
 		}
 		
 		// 检查该等级牌堆是否有剩余卡牌
 		if gl.gameState.UnflippedCards[selectedLevel] <= 0 {
-			return errors.New("该等级牌堆已空，无法盲抽卡牌")
-		}
-		
-		// 从该等级牌堆顶抽取一张卡牌
-		if gl.gameState.UnflippedCards[selectedLevel] > 0 {
-			drawnCards := gl.drawCardsFromDeck(selectedLevel, 1)
-			if len(drawnCards) > 0 {
-				reservedCardID = drawnCards[0]
-			} else {
-				return errors.New("无法从牌堆获取卡牌")
-			}
-		} else {
-			return errors.New("该等级牌堆已空，无法盲抽卡牌")
+			return NewActionError(ErrDeckEmpty, "该等级牌堆已空，无法盲抽卡牌")
 		}
-	} else if cardID == "" {
-		// 兼容旧版本：空字符串表示随机选择等级
-		// 随机选择一个等级
-		availableLevels := []models.CardLevel{}
-		for level := models.CardLevel(1); level <= 3; level++ {
-			if gl.gameState.UnflippedCards[level] > 0 {
-				availableLevels = append(availableLevels, level)
-			}
-		}
-		
-		if len(availableLevels) == 0 {
-			return errors.New("所有牌堆都已空，无法盲抽卡牌")
-		}
-		
-		// 随机选择一个等级
-		randomLevelIndex := gl.getRandomInt(0, len(availableLevels)-1)
-		selectedLevel := availableLevels[randomLevelIndex]
 		
 		// 从该等级牌堆顶抽取一张卡牌
 		if gl.gameState.UnflippedCards[selectedLevel] > 0 {
@@ -925,10 +864,10 @@ func (gl *GameLogic) ReserveCard(playerID string, cardID string, goldX, goldY in
 			if len(drawnCards) > 0 {
 				reservedCardID = drawnCards[0]
 			} else {
-				return errors.New("无法从牌堆获取卡牌")
+				return NewActionError(ErrDeckEmpty, "无法从牌堆获取卡牌")
 			}
 		} else {
-			return errors.New("该等级牌堆已空，无法盲抽卡牌")
+			return NewActionError(ErrDeckEmpty, "该等级牌堆已空，无法盲抽卡牌")
 		}
 	} else {
 		// 保留场上已翻开的卡牌
@@ -954,7 +893,7 @@ func (gl *GameLogic) ReserveCard(playerID string, cardID string, goldX, goldY in
 		}
 		
 		if !cardFound {
-			return errors.New("卡牌不存在或无法保留")
+			return NewActionError(ErrCardNotFound, "卡牌不存在或无法保留")
 		}
 		
 		reservedCardID = cardID
@@ -972,10 +911,20 @@ func (gl *GameLogic) ReserveCard(playerID string, cardID string, goldX, goldY in
 	
 	// 将卡牌添加到玩家保留区
 	gl.gameState.Players[playerIndex].ReservedCards = append(gl.gameState.Players[playerIndex].ReservedCards, reservedCardID)
+	if cmd.CardID == "" {
+		// 盲抽的卡只有自己可见，直到打出
+		gl.gameState.Players[playerIndex].BlindReservedCards = append(gl.gameState.Players[playerIndex].BlindReservedCards, reservedCardID)
+	}
+	gl.emit(CardReserved{
+		PlayerID: playerID,
+		CardID:   reservedCardID,
+		Level:    gl.gameState.CardDetails[reservedCardID].Level,
+		FromDeck: cmd.CardID == "",
+		Gold:     cmd.Gold,
+	})
 	
 	// 调用回合结束处理函数
 	if err := gl.HandleTurnEnd(); err != nil {
-		fmt.Printf("回合结束处理失败: %v\n", err)
 		return err
 	}
 	
@@ -983,31 +932,51 @@ func (gl *GameLogic) ReserveCard(playerID string, cardID string, goldX, goldY in
 }
 
 // SpendPrivilege 花费特权指示物
-func (gl *GameLogic) SpendPrivilege(playerID string, privilegeCount int, gemPositions []map[string]any) error {
+func (gl *GameLogic) SpendPrivilege(playerID string, cmd SpendPrivilegeCmd) error {
+	return gl.apply(GameAction{Type: ActionSpendPrivilege, PlayerID: playerID, SpendPrivilege: &cmd})
+}
+
+func (gl *GameLogic) spendPrivilege(playerID string, cmd SpendPrivilegeCmd) error {
 	if gl.gameState.Status == models.GameStatusFinished {
-		return errors.New("游戏已结束")
+		return NewActionError(ErrGameFinished, "游戏已结束")
 	}
 
-	// 可选动作顺序限制：若本回合已补充版图，则不能花费特权
-	if gl.gameState.RefilledThisTurn {
-		return errors.New("本回合已补充版图，不能使用特权指示物")
-	}
 	playerIndex := gl.getPlayerIndex(playerID)
 	if playerIndex == -1 {
-		return errors.New("玩家不存在")
+		return NewActionError(ErrPlayerNotFound, "玩家不存在")
 	}
 	
 	if gl.gameState.CurrentPlayerIndex != playerIndex {
-		return errors.New("不是该玩家的回合")
+		return NewActionError(ErrNotYourTurn, "不是该玩家的回合")
+	}
+
+	// 可选动作顺序限制：若本回合已补充版图，则不能花费特权
+	if gl.gameState.TurnPhase == models.PhaseMandatory {
+		return NewActionError(ErrWrongPhase, "本回合已补充版图，不能使用特权指示物")
+	}
+	if err := gl.checkPhase(models.PhaseOptional); err != nil {
+		return err
 	}
 	
 	player := &gl.gameState.Players[playerIndex]
+	privilegeCount := len(cmd.Positions)
+	if privilegeCount < 1 {
+		return NewActionError(ErrInvalidGemCount, "至少需要花费一个特权指示物")
+	}
 	if player.PrivilegeTokens < privilegeCount {
-		return errors.New("特权指示物不足")
+		return NewActionError(ErrInsufficientPrivileges, "特权指示物不足")
 	}
-	
-	if len(gemPositions) != privilegeCount {
-		return errors.New("选择的宝石数量与特权数量不匹配")
+
+	// 每个特权拿取一个非黄金宝石，与拿取宝石使用同一校验；多个特权不能选择同一位置
+	seen := make(map[models.Coord]bool, privilegeCount)
+	for _, pos := range cmd.Positions {
+		if err := gl.validateGemLine([]models.Coord{pos}); err != nil {
+			return err
+		}
+		if seen[pos] {
+			return NewActionError(ErrInvalidPosition, "不能重复选择同一位置")
+		}
+		seen[pos] = true
 	}
 	
 	// 扣除特权指示物
@@ -1015,51 +984,60 @@ func (gl *GameLogic) SpendPrivilege(playerID string, privilegeCount int, gemPosi
 	gl.gameState.AvailablePrivilegeTokens += privilegeCount
 	
 	// 将宝石添加到玩家手中
-	for _, pos := range gemPositions {
-		x, xOk := pos["x"].(float64)
-		y, yOk := pos["y"].(float64)
-		if !xOk || !yOk {
-			return errors.New("无效的宝石位置")
-		}
-		
-		rowIndex, colIndex := int(x), int(y)
-		if rowIndex < 0 || rowIndex >= 5 || colIndex < 0 || colIndex >= 5 {
-			return errors.New("宝石位置超出范围")
-		}
-		
+	var takenGems []models.GemType
+	for _, pos := range cmd.Positions {
+		rowIndex, colIndex := pos.X, pos.Y
 		gemType := gl.gameState.GemBoard[rowIndex][colIndex]
-		if gemType == "" {
-			return errors.New("该位置没有宝石")
-		}
 		
 		// 将宝石添加到玩家手中
 		player.Gems[gemType]++
+		takenGems = append(takenGems, gemType)
 		
 		// 从版图上移除宝石
 		gl.gameState.GemBoard[rowIndex][colIndex] = ""
 	}
+	gl.emit(PrivilegeSpent{PlayerID: playerID, Positions: cmd.Positions, Gems: takenGems})
+
+	// 花费特权可能拿走版图上最后可拿取的宝石，此时同样需要强制补充或处理僵局
+	gl.ensureMandatoryAction()
 	
 	return nil
 }
 
 // RefillBoard 补充版图
 func (gl *GameLogic) RefillBoard(playerID string) error {
+	return gl.apply(GameAction{Type: ActionRefillBoard, PlayerID: playerID})
+}
+
+func (gl *GameLogic) refillBoard(playerID string) error {
 	if gl.gameState.Status == models.GameStatusFinished {
-		return errors.New("游戏已结束")
+		return NewActionError(ErrGameFinished, "游戏已结束")
 	}
 
 	if len(gl.gameState.GemBag) == 0 {
-		return errors.New("宝石袋子为空，无法补充版图")
+		return NewActionError(ErrBagEmpty, "宝石袋子为空，无法补充版图")
 	}
 	playerIndex := gl.getPlayerIndex(playerID)
 	if playerIndex == -1 {
-		return errors.New("玩家不存在")
+		return NewActionError(ErrPlayerNotFound, "玩家不存在")
 	}
 	
 	if gl.gameState.CurrentPlayerIndex != playerIndex {
-		return errors.New("不是该玩家的回合")
+		return NewActionError(ErrNotYourTurn, "不是该玩家的回合")
 	}
+
+	if err := gl.checkPhase(models.PhaseOptional); err != nil {
+		return err
+	}
+
+	placed := gl.fillBoardFromBag(playerID)
+	gl.emit(BoardRefilled{PlayerID: playerID, Placed: placed})
 	
+	return nil
+}
+
+// 洗乱袋子并按顺序补充版图，之后只能执行必选动作，对手获得特权；返回放回版图的宝石数量
+func (gl *GameLogic) fillBoardFromBag(playerID string) int {
 	// 按照指定顺序补充宝石版图
 	refillOrder := [][]int{
 		{2, 2}, {3, 2}, // 2,2 至 3,2（往下）
@@ -1079,6 +1057,7 @@ func (gl *GameLogic) RefillBoard(playerID string) error {
 	}
 	
 	// 从宝石袋子中按顺序补充宝石
+	placed := 0
 	for _, pos := range refillOrder {
 		x, y := pos[0], pos[1]
 		if gl.gameState.GemBoard[x][y] == models.GemType("") {
@@ -1086,32 +1065,43 @@ func (gl *GameLogic) RefillBoard(playerID string) error {
 			gemType := gl.gameState.GemBag[0]
 			gl.gameState.GemBag = gl.gameState.GemBag[1:] // 移除已取出的宝石
 			gl.gameState.GemBoard[x][y] = gemType
+			placed++
 			if len(gl.gameState.GemBag) == 0 {
 				break
 			}
 		}
 	}
 
-	// 标记本回合已补充版图
+	// 标记本回合已补充版图，此后只能执行必选动作
 	gl.gameState.RefilledThisTurn = true
+	gl.gameState.TurnPhase = models.PhaseMandatory
 
 	// 对手获得特权指示物（统一使用GrantOpponentPrivilege）
-	_ = gl.GrantOpponentPrivilege(gl.gameState.Players[playerIndex].ID)
+	_ = gl.GrantOpponentPrivilege(playerID)
 	
-	return nil
+	return placed
+}
+
+// 把宝石放回袋子：按固定的宝石顺序追加而不是按 map 遍历顺序，保证相同种子重放时袋子顺序一致
+func (gl *GameLogic) returnGemsToBag(gems map[models.GemType]int) {
+	for _, supply := range gemSupply {
+		for i := 0; i < gems[supply.Gem]; i++ {
+			gl.gameState.GemBag = append(gl.gameState.GemBag, supply.Gem)
+		}
+	}
 }
 
 // 检查玩家是否可以购买卡牌
 func (gl *GameLogic) CanPlayerBuyCard(playerID string, cardID string) (bool, string, error) {
 	player := gl.getPlayer(playerID)
 	if player == nil {
-		return false, "", errors.New("玩家不存在")
+		return false, "", NewActionError(ErrPlayerNotFound, "玩家不存在")
 	}
 	
 	// 获取卡牌信息
 	card, exists := gl.gameState.CardDetails[cardID]
 	if !exists {
-		return false, "", errors.New("卡牌不存在")
+		return false, "", NewActionError(ErrCardNotFound, "卡牌不存在")
 	}
 	
 	// 计算总费用（考虑奖励优惠）
@@ -1176,144 +1166,124 @@ func getGemDisplayName(gemType models.GemType) string {
 	}
 }
 
-// 验证支付计划是否有效
-func (gl *GameLogic) validatePaymentPlan(player *models.Player, paymentPlan map[string]any, requiredGems map[models.GemType]int) bool {
-	// 计算支付计划中的总支付金额
-	var totalPaid, goldRequired, goldPaid int
+// 验证支付计划是否有效：每种颜色不超过应付数量，差额恰好由黄金补足
+func (gl *GameLogic) validatePaymentPlan(player *models.Player, paymentPlan map[models.GemType]int, requiredGems map[models.GemType]int) bool {
 	for gemType, count := range paymentPlan {
-		var countInt int
-		if countFloat, ok := count.(float64); ok {
-			countInt = int(countFloat)
-			totalPaid += countInt
-		}
-		// 检查玩家是否有足够的宝石
-		if player.Gems[models.GemType(gemType)] < countInt {
+		// 检查数量合法且玩家有足够的宝石
+		if count < 0 || player.Gems[gemType] < count {
 			return false
 		}
-		if gemType == "gold" {
-			goldPaid = countInt
-			continue
-		}
-		// 检查是否需要用黄金补足
-		required := requiredGems[models.GemType(gemType)]
-		if required > countInt {
-			goldRequired += required - countInt
+		// 不能支付不需要的颜色或超出应付数量
+		if gemType != models.GemGold && count > requiredGems[gemType] {
+			return false
 		}
 	}
 
-	// 检查支付的黄金是否足够
-	if goldPaid < goldRequired {
-		return false
-	}
-	
-	// 计算需要的总金额
-	var totalRequired int
-	for _, count := range requiredGems {
-		totalRequired += count
+	// 计划中未列出的颜色视为全部由黄金支付
+	goldRequired := 0
+	for gemType, required := range requiredGems {
+		goldRequired += required - paymentPlan[gemType]
 	}
-	
-	return totalPaid == totalRequired
+	return paymentPlan[models.GemGold] == goldRequired
 }
 
 // 从玩家扣除支付计划中的宝石和黄金
-func (gl *GameLogic) deductPaymentFromPlayer(player *models.Player, paymentPlan map[string]any) {
+func (gl *GameLogic) deductPaymentFromPlayer(player *models.Player, paymentPlan map[models.GemType]int) {
 	for gemType, count := range paymentPlan {
-		if countFloat, ok := count.(float64); ok {
-			countInt := int(countFloat)
-			if gemType == "gold" {
-				player.Gems[models.GemGold] -= countInt
-			} else {
-				player.Gems[models.GemType(gemType)] -= countInt
-			}
-		}
+		player.Gems[gemType] -= count
 	}
 }
 
-// BuyCardWithPaymentPlanAndEffects 购买发展卡（带支付计划与特效一次性结算）
-func (gl *GameLogic) BuyCardWithPaymentPlanAndEffects(playerID string, data map[string]any) error {
+// BuyCard 购买发展卡（按支付计划付款，并结算卡牌效果）
+func (gl *GameLogic) BuyCard(playerID string, cmd BuyCardCmd) error {
+	return gl.apply(GameAction{Type: ActionBuyCard, PlayerID: playerID, BuyCard: &cmd})
+}
+
+func (gl *GameLogic) buyCard(playerID string, cmd BuyCardCmd) error {
 	if gl.gameState.Status == models.GameStatusFinished {
-		return errors.New("游戏已结束")
+		return NewActionError(ErrGameFinished, "游戏已结束")
 	}
 
 	playerIndex := gl.getPlayerIndex(playerID)
 	if playerIndex == -1 {
-		return errors.New("玩家不存在")
+		return NewActionError(ErrPlayerNotFound, "玩家不存在")
 	}
 	
 	if gl.gameState.CurrentPlayerIndex != playerIndex {
-		return errors.New("不是该玩家的回合")
+		return NewActionError(ErrNotYourTurn, "不是该玩家的回合")
 	}
-	
-	// 获取卡牌ID
-	cardID, ok := data["cardId"].(string)
-	if !ok {
-		return errors.New("缺少卡牌ID")
+
+	if err := gl.checkPhase(models.PhaseOptional, models.PhaseMandatory); err != nil {
+		return err
 	}
 	
-	// 获取卡牌信息
-	card, exists := gl.gameState.CardMap[cardID]
-	if !exists {
-		return errors.New("卡牌不存在")
+	// 获取卡牌ID
+	cardID := cmd.CardID
+	if cardID == "" {
+		return NewActionError(ErrInvalidAction, "缺少卡牌ID")
 	}
 	
 	// 获取玩家
 	player := gl.getPlayer(playerID)
 	if player == nil {
-		return errors.New("玩家不存在")
+		return NewActionError(ErrPlayerNotFound, "玩家不存在")
 	}
 	
-	// 获取支付计划
-	paymentPlan, ok := data["paymentPlan"].(map[string]any)
-	if !ok {
-		return errors.New("缺少支付计划")
+	// 只能购买场上翻开的卡牌或自己的保留卡（与合法动作列表一致）
+	if card, ok := gl.gameState.CardMap[cardID]; ok && !canAssignWildcard(player, card) {
+		return NewActionError(ErrCardNotFound, "百搭卡需要先拥有至少一张有颜色的发展卡")
+	}
+	if !gl.isCardBuyable(player, cardID) {
+		return NewActionError(ErrCardNotFound, "卡牌不存在或无法购买")
 	}
+	card := gl.gameState.CardMap[cardID]
 	
 	// 计算应支付费用
 	requiredGems := gl.calculateRequiredGems(&DevelopmentCardData{
-		ID:        card.ID,
-		Level:     card.Level,
-		Code:      card.Code,
-		Color:     card.Color,
-		Points:    card.Points,
-		Crowns:    card.Crowns,
-		Bonus:     card.Bonus,
-		Cost:      card.Cost,
-		Effects:   card.Effects,
-		IsSpecial: card.IsSpecial,
+		ID:         card.ID,
+		Level:      card.Level,
+		Code:       card.Code,
+		Color:      card.Color,
+		Points:     card.Points,
+		Crowns:     card.Crowns,
+		Bonus:      card.Bonus,
+		Cost:       card.Cost,
+		Effects:    card.Effects,
+		IsSpecial:  card.IsSpecial,
+		BonusCount: card.BonusCount,
+		ImagePath:  card.ImagePath,
 	}, player)
 	
+	// 获取支付计划，自动支付时使用推荐的第一个方案
+	paymentPlan := cmd.Payment
+	if cmd.AutoPay {
+		plans := rankPaymentPlans(player, paymentPlans(player, requiredGems))
+		if len(plans) == 0 {
+			return NewActionError(ErrInsufficientGems, "宝石不足，无法购买该卡牌")
+		}
+		paymentPlan = plans[0]
+	}
+	
 	// 验证支付计划是否完整
 	if !gl.validatePaymentPlan(player, paymentPlan, requiredGems) {
-		return errors.New("支付计划无效或宝石不足")
+		return NewActionError(ErrInsufficientGems, "支付计划无效或宝石不足")
 	}
 	
 	// 扣除宝石和黄金
 	gl.deductPaymentFromPlayer(player, paymentPlan)
 	
 	// 将宝石放回袋子
-	for gemType, count := range paymentPlan {
-		if countFloat, ok := count.(float64); ok {
-			countInt := int(countFloat)
-			// 将宝石添加到宝石袋子中
-			for i := 0; i < countInt; i++ {
-				gl.gameState.GemBag = append(gl.gameState.GemBag, models.GemType(gemType))
-			}
-		}
-	}
+	gl.returnGemsToBag(paymentPlan)
 	
 	// 将卡牌添加到玩家手中
 	player.DevelopmentCards = append(player.DevelopmentCards, cardID)
-	cardBonus := 1
-	// 对于 cardID 以 k 开头的卡牌，bonus=2
-	if strings.HasPrefix(cardID, "k") {
-		cardBonus = 2
-	}
-	player.Bonus[card.Bonus] += cardBonus
+	player.Bonus[card.Bonus] += cardBonusCount(card)
 	player.Points += card.Points
 	player.Crowns += card.Crowns
 	
 	// 检查卡牌是否在保留区域，如果是则从保留区域移除
-	if gl.removeCardFromReserved(playerID, cardID) {
+	fromReserve := gl.removeCardFromReserved(playerID, cardID)
+	if fromReserve {
 		// 卡牌在保留区域，不需要补充翻开的卡牌
 	} else {
 		// 卡牌在场上，从场上移除并记录位置信息
@@ -1325,282 +1295,30 @@ func (gl *GameLogic) BuyCardWithPaymentPlanAndEffects(playerID string, data map[
 		}
 	}
 	
-	// 先结算需要玩家即时确认的一次性效果（本次仅额外token）
-	gl.resolveImmediateEffects(&DevelopmentCardData{
-		ID:        card.ID,
-		Level:     card.Level,
-		Code:      card.Code,
-		Color:     card.Color,
-		Points:    card.Points,
-		Crowns:    card.Crowns,
-		Bonus:     card.Bonus,
-		Cost:      card.Cost,
-		Effects:   card.Effects,
-		IsSpecial: card.IsSpecial,
-	}, playerID, data)
-
-	// 再结算无需确认的效果（新回合、获得特权等）
-	gl.resolveCardEffects(&DevelopmentCardData{
-		ID:        card.ID,
-		Level:     card.Level,
-		Code:      card.Code,
-		Color:     card.Color,
-		Points:    card.Points,
-		Crowns:    card.Crowns,
-		Bonus:     card.Bonus,
-		Cost:      card.Cost,
-		Effects:   card.Effects,
-		IsSpecial: card.IsSpecial,
-	}, playerID)
+	payment := make(map[models.GemType]int)
+	for gemType, count := range paymentPlan {
+		if count > 0 {
+			payment[gemType] = count
+		}
+	}
+	gl.emit(CardPurchased{PlayerID: playerID, CardID: cardID, Level: card.Level, Payment: payment, FromReserve: fromReserve})
+	
+	// 触发卡牌效果并开启需要玩家选择的决策（额外token/窃取/百搭颜色/贵族），用随购买附带的选择预先作答
+	if err := gl.openCardDecisions(playerID, &card); err != nil {
+		return err
+	}
+	if err := gl.resolvePrepackedChoices(playerID, cmd.Choices); err != nil {
+		return err
+	}
 	
 	// 调用回合结束处理函数
 	if err := gl.HandleTurnEnd(); err != nil {
-		fmt.Printf("回合结束处理失败: %v\n", err)
 		return err
 	}
 	
 	return nil
 }
 
-// 处理需要玩家二次确认的特效（额外token/窃取/百搭颜色）
-// 本次仅实现额外token
-func (gl *GameLogic) resolveImmediateEffects(card *DevelopmentCardData, playerID string, data map[string]any) {
-    player := gl.getPlayer(playerID)
-    if player == nil {
-        return
-    }
-
-    var effectsData map[string]any
-    if v, ok := data["effects"].(map[string]any); ok {
-        effectsData = v
-    } else {
-        effectsData = map[string]any{}
-    }
-
-    // 标记卡牌本身是否包含对应效果
-    hasSteal := false
-
-    for _, effect := range card.Effects {
-        switch effect {
-        case models.ExtraToken:
-            gl.handleExtraTokenEffect(playerID, card.Color, effectsData)
-        case models.Steal:
-            hasSteal = true
-            gl.handleStealEffect(playerID, effectsData)
-        case models.Wildcard:
-            gl.handleWildcardEffect(playerID, card, effectsData)
-        default:
-            // 其他需要确认的效果后续实现
-        }
-    }
-
-    // 若卡牌本身不含窃取效果，但前端传来了窃取（例如 noble1 触发），也应结算一次
-    if !hasSteal {
-        if _, ok := effectsData["steal"].(map[string]any); ok {
-            gl.handleStealEffect(playerID, effectsData)
-        }
-    }
-
-    // 处理贵族选择（若传入）
-    if nobleRaw, ok := effectsData["noble"].(map[string]any); ok {
-        gl.handleNobleSelection(playerID, nobleRaw)
-    }
-}
-
-// 处理贵族选择与效果结算（noble2: +2分+新回合；noble3: +2分+特权；noble4: +3分）
-func (gl *GameLogic) handleNobleSelection(playerID string, nobleData map[string]any) bool {
-    id, _ := nobleData["id"].(string)
-    if id == "" {
-        return false
-    }
-    player := gl.getPlayer(playerID)
-    if player == nil {
-        return false
-    }
-
-    for _, n := range player.Nobles {
-        if n == id {
-            return false
-        }
-    }
-
-    switch id {
-    case "noble1":
-        player.Points += 2
-        // noble1 的窃取效果在购买卡牌时处理，这里只处理分数
-    case "noble2":
-        player.Points += 2
-        if gl.gameState.ExtraTurns == nil {
-            gl.gameState.ExtraTurns = map[string]int{}
-        }
-        gl.gameState.ExtraTurns[playerID]++
-    case "noble3":
-        player.Points += 2
-        _ = gl.TakePrivilegeToken(playerID)
-    case "noble4":
-        player.Points += 3
-    default:
-        return false
-    }
-
-    player.Nobles = append(player.Nobles, id)
-    // 从场上可用贵族中移除
-    var filtered []string
-    for _, nid := range gl.gameState.AvailableNobles {
-        if nid != id {
-            filtered = append(filtered, nid)
-        }
-    }
-    gl.gameState.AvailableNobles = filtered
-    return true
-}
-
-// handleExtraTokenEffect 处理额外token效果：
-// - 前端可在effects.extraToken传入 { selectedGem: {x:int, y:int} } 或 { skipped: true }
-// - 只允许拿取与卡牌颜色相同的一个token
-func (gl *GameLogic) handleExtraTokenEffect(playerID string, cardColor models.GemType, effectsData map[string]any) bool {
-    extraRaw, ok := effectsData["extraToken"].(map[string]any)
-    // 未提供数据则视为无效
-    if !ok {
-        return false
-    }
-
-	// 跳过则直接返回成功
-    if skipped, ok := extraRaw["skipped"].(bool); ok && skipped {
-        return true
-    }
-
-    sel, ok := extraRaw["selectedGem"].(map[string]any)
-    if !ok {
-        return false
-    }
-
-    var x, y int
-    if xv, ok := sel["x"].(float64); ok { x = int(xv) }
-    if yv, ok := sel["y"].(float64); ok { y = int(yv) }
-
-    if x < 0 || y < 0 || x >= len(gl.gameState.GemBoard) || y >= len(gl.gameState.GemBoard[0]) {
-        return false
-    }
-
-	// 判断宝石位置有效性
-    gem := gl.gameState.GemBoard[x][y]
-    if gem == "" {
-        return false
-    }
-
-	// 判断宝石颜色是否匹配
-    if gem != cardColor || gem == models.GemGold {
-        return false
-    }
-
-    gl.gameState.GemBoard[x][y] = ""
-    player := gl.getPlayer(playerID)
-    if player == nil {
-        return false
-    }
-    player.Gems[gem]++
-    return true
-}
-
-// handleStealEffect 处理窃取效果：
-// - 前端通过 effects.steal 传入 { gemType: 'white'|'blue'|'green'|'red'|'black' } 或 { skipped: true }
-// - 从对手处窃取一个对应的非黄金token
-func (gl *GameLogic) handleStealEffect(playerID string, effectsData map[string]any) bool {
-    stealRaw, ok := effectsData["steal"].(map[string]any)
-    if !ok {
-        return false
-    }
-
-    if skipped, ok := stealRaw["skipped"].(bool); ok && skipped {
-        return true
-    }
-
-    gemStr, ok := stealRaw["gemType"].(string)
-    if !ok {
-        return false
-    }
-    gemType := models.GemType(gemStr)
-    if gemType == models.GemGold || gemType == "" {
-		return false
-    }
-
-    // 找到对手
-    opponentIdx := 1 - gl.getPlayerIndex(playerID)
-    if opponentIdx < 0 || opponentIdx >= len(gl.gameState.Players) {
-        return false
-    }
-    opponent := &gl.gameState.Players[opponentIdx]
-    if opponent.Gems[gemType] <= 0 {
-        return false
-    }
-
-    // 执行窃取
-    opponent.Gems[gemType]--
-    player := gl.getPlayer(playerID)
-    if player == nil {
-        // 回滚
-        opponent.Gems[gemType]++
-        return false
-    }
-    player.Gems[gemType]++
-    return true
-}
-
-// handleWildcardEffect 处理百搭颜色效果：
-// - 前端通过 effects.wildcard 传入 { color: 'white'|'blue'|'green'|'red'|'black' } 或 { skipped: true }
-// - 调整玩家bonus：将本卡默认计入的灰色bonus转移到所选颜色
-func (gl *GameLogic) handleWildcardEffect(playerID string, card *DevelopmentCardData, effectsData map[string]any) bool {
-    wildRaw, ok := effectsData["wildcard"].(map[string]any)
-    if !ok {
-        return false
-    }
-    if skipped, ok := wildRaw["skipped"].(bool); ok && skipped {
-        return true
-    }
-    colorStr, ok := wildRaw["color"].(string)
-    if !ok {
-        return false
-    }
-    chosen := models.GemType(colorStr)
-    switch chosen {
-    case models.GemWhite, models.GemBlue, models.GemGreen, models.GemRed, models.GemBlack:
-        // ok
-    default:
-        return false
-    }
-
-    player := gl.getPlayer(playerID)
-    if player == nil {
-        return false
-    }
-
-    // 将灰色bonus（若已加）转移为所选颜色
-    if card.Bonus == models.GemGray {
-        if player.Bonus[models.GemGray] > 0 {
-            player.Bonus[models.GemGray]--
-        }
-    }
-    card.Color = chosen
-    card.Bonus = chosen
-    player.Bonus[chosen]++
-
-    // 同步运行时卡牌详情映射，便于前端tooltip正确归类
-    if cd, ok := gl.gameState.CardDetails[card.ID]; ok {
-        cd.Bonus = chosen
-        cd.Color = chosen
-        gl.gameState.CardDetails[card.ID] = cd
-    }
-    if cm, ok := gl.gameState.CardMap[card.ID]; ok {
-        cm.Bonus = chosen
-        cm.Color = chosen
-        gl.gameState.CardMap[card.ID] = cm
-    }
-    // 移除灰色奖励显示
-    delete(player.Bonus, models.GemGray)
-    return true
-}
-
 // 从玩家的保留区域移除卡牌
 func (gl *GameLogic) removeCardFromReserved(playerID string, cardID string) bool {
 	player := gl.getPlayer(playerID)
@@ -1613,6 +1331,12 @@ func (gl *GameLogic) removeCardFromReserved(playerID string, cardID string) bool
 		if reservedCardID == cardID {
 			// 从保留区域移除卡牌
 			player.ReservedCards = append(player.ReservedCards[:i], player.ReservedCards[i+1:]...)
+			for j, blindCardID := range player.BlindReservedCards {
+				if blindCardID == cardID {
+					player.BlindReservedCards = append(player.BlindReservedCards[:j], player.BlindReservedCards[j+1:]...)
+					break
+				}
+			}
 			return true
 		}
 	}
@@ -0,0 +1,545 @@
+package game
+
+import (
+	"fmt"
+	"testing"
+
+	"splendor-duel-backend/internal/models"
+)
+
+const testBoardSize = 5
+
+// 八个方向（含反向），用于枚举任意起点出发的直线
+var allDirections = []models.Coord{
+	{X: 0, Y: 1}, {X: 1, Y: 0}, {X: 1, Y: 1}, {X: 1, Y: -1},
+	{X: 0, Y: -1}, {X: -1, Y: 0}, {X: -1, Y: -1}, {X: -1, Y: 1},
+}
+
+// 每格都是蓝宝石的版图
+func fullBoard() [][]models.GemType {
+	board := make([][]models.GemType, testBoardSize)
+	for x := range board {
+		board[x] = make([]models.GemType, testBoardSize)
+		for y := range board[x] {
+			board[x][y] = models.GemBlue
+		}
+	}
+	return board
+}
+
+func boardLogic(board [][]models.GemType) *GameLogic {
+	return NewGameLogic(&models.GameState{GemBoard: board})
+}
+
+func inBoard(c models.Coord) bool {
+	return c.X >= 0 && c.X < testBoardSize && c.Y >= 0 && c.Y < testBoardSize
+}
+
+func allCells() []models.Coord {
+	var cells []models.Coord
+	for x := 0; x < testBoardSize; x++ {
+		for y := 0; y < testBoardSize; y++ {
+			cells = append(cells, models.Coord{X: x, Y: y})
+		}
+	}
+	return cells
+}
+
+func line(start, dir models.Coord, length int) []models.Coord {
+	coords := make([]models.Coord, length)
+	for i := range coords {
+		coords[i] = models.Coord{X: start.X + dir.X*i, Y: start.Y + dir.Y*i}
+	}
+	return coords
+}
+
+func reversed(coords []models.Coord) []models.Coord {
+	out := make([]models.Coord, len(coords))
+	for i, c := range coords {
+		out[len(coords)-1-i] = c
+	}
+	return out
+}
+
+// 检查校验结果的错误码，want 为空表示应当通过
+func expectCode(t *testing.T, err error, want ErrorCode, format string, args ...any) {
+	t.Helper()
+	if got := CodeOf(err, ""); got != want {
+		t.Errorf("%s: 期望 %q，实际 %q (%v)", fmt.Sprintf(format, args...), want, got, err)
+	}
+}
+
+// 从每个格子出发、沿八个方向、长度 1-3 的直线：全部在版图内时合法（任意顺序），否则位置超出范围
+func TestValidateGemLineEveryStraightLine(t *testing.T) {
+	gl := boardLogic(fullBoard())
+	for _, start := range allCells() {
+		for _, dir := range allDirections {
+			for length := 1; length <= 3; length++ {
+				coords := line(start, dir, length)
+				want := ErrorCode("")
+				for _, c := range coords {
+					if !inBoard(c) {
+						want = ErrInvalidPosition
+					}
+				}
+				expectCode(t, gl.validateGemLine(coords), want, "%v", coords)
+				expectCode(t, gl.validateGemLine(reversed(coords)), want, "%v", reversed(coords))
+				if length == 3 {
+					shuffled := []models.Coord{coords[1], coords[0], coords[2]}
+					expectCode(t, gl.validateGemLine(shuffled), want, "%v", shuffled)
+				}
+			}
+		}
+	}
+}
+
+// 任意两个不同的格子：只有相邻（横、竖、斜）时才能一起拿取
+func TestValidateGemLineEveryPair(t *testing.T) {
+	gl := boardLogic(fullBoard())
+	for _, a := range allCells() {
+		for _, b := range allCells() {
+			if a == b {
+				continue
+			}
+			dx, dy := b.X-a.X, b.Y-a.Y
+			want := ErrGemsNotInLine
+			if dx >= -1 && dx <= 1 && dy >= -1 && dy <= 1 {
+				want = ""
+			}
+			expectCode(t, gl.validateGemLine([]models.Coord{a, b}), want, "%v %v", a, b)
+		}
+	}
+}
+
+// 任意三个不同的格子：只有构成连续直线时才合法，其余（折线、L形、有间隔）都不在同一直线上
+func TestValidateGemLineEveryTriple(t *testing.T) {
+	straight := make(map[[3]models.Coord]bool)
+	for _, start := range allCells() {
+		for _, dir := range allDirections {
+			coords := line(start, dir, 3)
+			if inBoard(coords[2]) {
+				straight[[3]models.Coord{coords[0], coords[1], coords[2]}] = true
+			}
+		}
+	}
+
+	gl := boardLogic(fullBoard())
+	cells := allCells()
+	checked := 0
+	for i := range cells {
+		for j := range cells {
+			for k := range cells {
+				if i == j || j == k || i == k {
+					continue
+				}
+				triple := [3]models.Coord{cells[i], cells[j], cells[k]}
+				want := ErrGemsNotInLine
+				for _, perm := range [][3]int{{0, 1, 2}, {0, 2, 1}, {1, 0, 2}, {1, 2, 0}, {2, 0, 1}, {2, 1, 0}} {
+					if straight[[3]models.Coord{triple[perm[0]], triple[perm[1]], triple[perm[2]]}] {
+						want = ""
+					}
+				}
+				expectCode(t, gl.validateGemLine(triple[:]), want, "%v", triple)
+				checked++
+			}
+		}
+	}
+	if checked != 25*24*23 {
+		t.Fatalf("只检查了 %d 组", checked)
+	}
+}
+
+// 中间格为空时：选中空格报空位，跳过空格拿两端报不在同一直线上
+func TestValidateGemLineGaps(t *testing.T) {
+	for _, start := range allCells() {
+		for _, dir := range allDirections {
+			coords := line(start, dir, 3)
+			if !inBoard(coords[2]) {
+				continue
+			}
+			board := fullBoard()
+			board[coords[1].X][coords[1].Y] = ""
+			gl := boardLogic(board)
+
+			expectCode(t, gl.validateGemLine(coords), ErrEmptyCell, "中间为空 %v", coords)
+			expectCode(t, gl.validateGemLine([]models.Coord{coords[0], coords[2]}), ErrGemsNotInLine, "跳过空格 %v", coords)
+			expectCode(t, gl.validateGemLine(coords[1:2]), ErrEmptyCell, "只选空格 %v", coords[1])
+			expectCode(t, gl.validateGemLine(coords[:1]), "", "空格旁的单个宝石 %v", coords[0])
+		}
+	}
+}
+
+// 黄金不能通过拿取宝石获得，无论单独拿取还是位于直线中
+func TestValidateGemLineGold(t *testing.T) {
+	for _, cell := range allCells() {
+		board := fullBoard()
+		board[cell.X][cell.Y] = models.GemGold
+		gl := boardLogic(board)
+
+		expectCode(t, gl.validateGemLine([]models.Coord{cell}), ErrGoldNotTakeable, "单个黄金 %v", cell)
+		for _, dir := range allDirections {
+			for length := 2; length <= 3; length++ {
+				coords := line(cell, dir, length)
+				if !inBoard(coords[length-1]) {
+					continue
+				}
+				expectCode(t, gl.validateGemLine(coords), ErrGoldNotTakeable, "含黄金的直线 %v", coords)
+				expectCode(t, gl.validateGemLine(coords[1:]), "", "黄金旁的直线 %v", coords[1:])
+			}
+		}
+	}
+}
+
+// 重复选择同一格：任意格子重复两次或三次都被拒绝
+func TestValidateGemLineDuplicates(t *testing.T) {
+	gl := boardLogic(fullBoard())
+	for _, cell := range allCells() {
+		expectCode(t, gl.validateGemLine([]models.Coord{cell, cell}), ErrInvalidPosition, "重复 %v", cell)
+		expectCode(t, gl.validateGemLine([]models.Coord{cell, cell, cell}), ErrInvalidPosition, "三次重复 %v", cell)
+		for _, dir := range allDirections {
+			next := models.Coord{X: cell.X + dir.X, Y: cell.Y + dir.Y}
+			if inBoard(next) {
+				expectCode(t, gl.validateGemLine([]models.Coord{cell, next, cell}), ErrInvalidPosition, "重复 %v %v", cell, next)
+			}
+		}
+	}
+}
+
+func TestValidateGemLineCountAndRange(t *testing.T) {
+	gl := boardLogic(fullBoard())
+	tests := []struct {
+		name      string
+		positions []models.Coord
+		want      ErrorCode
+	}{
+		{"没有位置", nil, ErrInvalidGemCount},
+		{"四个连续位置", line(models.Coord{X: 0, Y: 0}, models.Coord{X: 0, Y: 1}, 4), ErrInvalidGemCount},
+		{"五个连续位置", line(models.Coord{X: 0, Y: 0}, models.Coord{X: 1, Y: 1}, 5), ErrInvalidGemCount},
+		{"行为负数", []models.Coord{{X: -1, Y: 0}}, ErrInvalidPosition},
+		{"列为负数", []models.Coord{{X: 0, Y: -1}}, ErrInvalidPosition},
+		{"行越界", []models.Coord{{X: 5, Y: 0}}, ErrInvalidPosition},
+		{"列越界", []models.Coord{{X: 0, Y: 5}}, ErrInvalidPosition},
+		{"角外", []models.Coord{{X: 5, Y: 5}}, ErrInvalidPosition},
+		{"直线延伸出版图", []models.Coord{{X: 0, Y: 3}, {X: 0, Y: 4}, {X: 0, Y: 5}}, ErrInvalidPosition},
+		{"斜线延伸出版图", []models.Coord{{X: 3, Y: 1}, {X: 4, Y: 0}, {X: 5, Y: -1}}, ErrInvalidPosition},
+		{"L形", []models.Coord{{X: 0, Y: 0}, {X: 0, Y: 1}, {X: 1, Y: 1}}, ErrGemsNotInLine},
+		{"折线", []models.Coord{{X: 0, Y: 0}, {X: 1, Y: 1}, {X: 2, Y: 1}}, ErrGemsNotInLine},
+		{"马步", []models.Coord{{X: 0, Y: 0}, {X: 1, Y: 2}}, ErrGemsNotInLine},
+		{"同行不相邻", []models.Coord{{X: 2, Y: 0}, {X: 2, Y: 1}, {X: 2, Y: 3}}, ErrGemsNotInLine},
+		{"横线", []models.Coord{{X: 2, Y: 1}, {X: 2, Y: 2}, {X: 2, Y: 3}}, ""},
+		{"反斜线", []models.Coord{{X: 0, Y: 4}, {X: 1, Y: 3}, {X: 2, Y: 2}}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expectCode(t, gl.validateGemLine(tt.positions), tt.want, "%v", tt.positions)
+		})
+	}
+}
+
+// 开局后由当前玩家行动、处于可选动作阶段的状态，版图替换为 board
+func playingState(t *testing.T, board [][]models.GemType) (models.GameState, string) {
+	t.Helper()
+	state, _, err := Apply(newTestState(1), GameAction{Type: ActionStartGame})
+	if err != nil {
+		t.Fatal(err)
+	}
+	state.GemBoard = board
+	return state, state.Players[state.CurrentPlayerIndex].ID
+}
+
+func TestTakeGemsRejectsInvalidLines(t *testing.T) {
+	board := fullBoard()
+	board[2][2] = models.GemGold
+	board[0][1] = ""
+	state, playerID := playingState(t, board)
+
+	tests := []struct {
+		name      string
+		positions []models.Coord
+		want      ErrorCode
+	}{
+		{"黄金", []models.Coord{{X: 2, Y: 2}}, ErrGoldNotTakeable},
+		{"空格", []models.Coord{{X: 0, Y: 1}}, ErrEmptyCell},
+		{"跳过空格", []models.Coord{{X: 0, Y: 0}, {X: 0, Y: 2}}, ErrGemsNotInLine},
+		{"越界", []models.Coord{{X: 4, Y: 4}, {X: 5, Y: 5}}, ErrInvalidPosition},
+		{"合法直线", []models.Coord{{X: 4, Y: 0}, {X: 4, Y: 1}, {X: 4, Y: 2}}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			action := GameAction{Type: ActionTakeGems, PlayerID: playerID, TakeGems: &TakeGemsCmd{Positions: tt.positions}}
+			next, _, err := Apply(state, action)
+			expectCode(t, err, tt.want, "%v", tt.positions)
+			if tt.want != "" {
+				return
+			}
+			for _, pos := range tt.positions {
+				if next.GemBoard[pos.X][pos.Y] != "" {
+					t.Errorf("%v 拿取后应为空", pos)
+				}
+			}
+			if got := NewGameLogic(&next).getPlayer(playerID).Gems[models.GemBlue]; got != 3 {
+				t.Errorf("应获得 3 个蓝宝石，实际 %d", got)
+			}
+		})
+	}
+}
+
+// 保留发展卡必须指定场上翻开的卡牌或牌堆等级
+func TestReserveCardRequiresCardOrDeck(t *testing.T) {
+	state, _, err := Apply(newTestState(3), GameAction{Type: ActionStartGame})
+	if err != nil {
+		t.Fatal(err)
+	}
+	gl := NewGameLogic(&state)
+	playerID := state.Players[state.CurrentPlayerIndex].ID
+	gold := gl.boardPositions(func(gem models.GemType) bool { return gem == models.GemGold })[0]
+
+	tests := []struct {
+		name string
+		cmd  ReserveCardCmd
+		want ErrorCode
+	}{
+		{"未指定卡牌或牌堆", ReserveCardCmd{Gold: gold}, ErrInvalidAction},
+		{"牌堆顶", ReserveCardCmd{DeckLevel: models.Level1, Gold: gold}, ""},
+		{"翻开的卡", ReserveCardCmd{CardID: state.FlippedCards[models.Level1][0], Gold: gold}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := tt.cmd
+			_, _, err := Apply(state, GameAction{Type: ActionReserveCard, PlayerID: playerID, ReserveCard: &cmd})
+			expectCode(t, err, tt.want, "%+v", tt.cmd)
+		})
+	}
+}
+
+// 花费特权与拿取宝石使用同一校验：每个特权拿取一个非黄金宝石，位置不必相邻但不能重复
+func TestSpendPrivilegeUsesGemValidation(t *testing.T) {
+	board := fullBoard()
+	board[2][2] = models.GemGold
+	board[0][1] = ""
+	board[4][4] = models.GemPearl
+
+	tests := []struct {
+		name       string
+		privileges int
+		positions  []models.Coord
+		want       ErrorCode
+	}{
+		{"单个宝石", 1, []models.Coord{{X: 0, Y: 0}}, ""},
+		{"单个珍珠", 1, []models.Coord{{X: 4, Y: 4}}, ""},
+		{"不相邻的两个宝石", 2, []models.Coord{{X: 0, Y: 0}, {X: 3, Y: 4}}, ""},
+		{"三个宝石", 3, []models.Coord{{X: 0, Y: 0}, {X: 1, Y: 3}, {X: 4, Y: 4}}, ""},
+		{"黄金", 1, []models.Coord{{X: 2, Y: 2}}, ErrGoldNotTakeable},
+		{"宝石与黄金", 2, []models.Coord{{X: 0, Y: 0}, {X: 2, Y: 2}}, ErrGoldNotTakeable},
+		{"空格", 1, []models.Coord{{X: 0, Y: 1}}, ErrEmptyCell},
+		{"越界", 1, []models.Coord{{X: 5, Y: 0}}, ErrInvalidPosition},
+		{"负坐标", 1, []models.Coord{{X: 0, Y: -1}}, ErrInvalidPosition},
+		{"重复位置", 2, []models.Coord{{X: 1, Y: 1}, {X: 1, Y: 1}}, ErrInvalidPosition},
+		{"特权不足", 1, []models.Coord{{X: 0, Y: 0}, {X: 1, Y: 0}}, ErrInsufficientPrivileges},
+		{"没有位置", 1, nil, ErrInvalidGemCount},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, playerID := playingState(t, board)
+			player := NewGameLogic(&state).getPlayer(playerID)
+			player.PrivilegeTokens = tt.privileges
+			state.AvailablePrivilegeTokens = 0
+
+			action := GameAction{Type: ActionSpendPrivilege, PlayerID: playerID, SpendPrivilege: &SpendPrivilegeCmd{Positions: tt.positions}}
+			next, _, err := Apply(state, action)
+			expectCode(t, err, tt.want, "%v", tt.positions)
+			if tt.want != "" {
+				return
+			}
+			after := NewGameLogic(&next).getPlayer(playerID)
+			if after.PrivilegeTokens != tt.privileges-len(tt.positions) {
+				t.Errorf("剩余特权应为 %d，实际 %d", tt.privileges-len(tt.positions), after.PrivilegeTokens)
+			}
+			for _, pos := range tt.positions {
+				if next.GemBoard[pos.X][pos.Y] != "" {
+					t.Errorf("%v 拿取后应为空", pos)
+				}
+			}
+		})
+	}
+}
+
+// 只能购买场上翻开的卡牌或自己的保留卡：牌堆中的卡、对手的保留卡与已购买的卡都被拒绝
+func TestBuyCardRejectsUnavailableCards(t *testing.T) {
+	state, _, err := Apply(newTestState(2), GameAction{Type: ActionStartGame})
+	if err != nil {
+		t.Fatal(err)
+	}
+	gl := NewGameLogic(&state)
+	playerID := state.Players[state.CurrentPlayerIndex].ID
+	player := gl.getPlayer(playerID)
+	opponent := gl.getOpponent(playerID)
+	for _, gem := range testGemOrder {
+		player.Gems[gem] = 10
+	}
+	player.Bonus[models.GemBlue] = 1 // 翻开的卡可能是百搭卡
+
+	faceUp := state.FlippedCards[models.Level1]
+	opponentReserved, ownReserved, owned, available := faceUp[0], faceUp[1], faceUp[2], faceUp[3]
+	state.FlippedCards[models.Level1] = []string{available}
+	opponent.ReservedCards = append(opponent.ReservedCards, opponentReserved)
+	player.ReservedCards = append(player.ReservedCards, ownReserved)
+	player.DevelopmentCards = append(player.DevelopmentCards, owned)
+
+	tests := []struct {
+		name   string
+		cardID string
+		want   ErrorCode
+	}{
+		{"牌堆中的卡", state.Level1Deck[0], ErrCardNotFound},
+		{"对手的保留卡", opponentReserved, ErrCardNotFound},
+		{"已购买的卡", owned, ErrCardNotFound},
+		{"不存在的卡", "no-such-card", ErrCardNotFound},
+		{"自己的保留卡", ownReserved, ""},
+		{"场上翻开的卡", available, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			action := GameAction{Type: ActionBuyCard, PlayerID: playerID, BuyCard: &BuyCardCmd{CardID: tt.cardID, AutoPay: true}}
+			_, _, err := Apply(state, action)
+			expectCode(t, err, tt.want, "%s", tt.cardID)
+		})
+	}
+	for _, action := range gl.LegalActions(playerID) {
+		if action.Type != ActionBuyCard {
+			continue
+		}
+		if id := action.BuyCard.CardID; id == state.Level1Deck[0] || id == opponentReserved || id == owned {
+			t.Errorf("合法动作中出现了不可购买的卡 %s", id)
+		}
+	}
+}
+
+// 卡牌带有未注册的效果时购买被拒绝，而不是忽略该效果
+func TestBuyCardRejectsUnregisteredEffect(t *testing.T) {
+	state, _, err := Apply(newTestState(2), GameAction{Type: ActionStartGame})
+	if err != nil {
+		t.Fatal(err)
+	}
+	playerID := state.Players[state.CurrentPlayerIndex].ID
+	player := NewGameLogic(&state).getPlayer(playerID)
+	for _, gem := range testGemOrder {
+		player.Gems[gem] = 10
+	}
+	cardID := state.FlippedCards[models.Level1][0]
+	card := state.CardMap[cardID]
+	card.Effects = append([]models.CardEffect{"no_such_effect"}, card.Effects...)
+	state.CardMap[cardID] = card
+
+	action := GameAction{Type: ActionBuyCard, PlayerID: playerID, BuyCard: &BuyCardCmd{CardID: cardID, AutoPay: true}}
+	if _, _, err := Apply(state, action); CodeOf(err, "") != ErrInternal {
+		t.Fatalf("未注册的效果应使购买被拒绝，实际 %v", err)
+	}
+}
+
+// 百搭卡只能选择玩家已拥有的发展卡颜色
+// 目录中ID最小的百搭卡
+func firstWildcardCard(t *testing.T, state models.GameState) string {
+	t.Helper()
+	var wildcardID string
+	for id, card := range state.CardMap {
+		for _, effect := range card.Effects {
+			if effect == models.Wildcard && (wildcardID == "" || id < wildcardID) {
+				wildcardID = id
+			}
+		}
+	}
+	if wildcardID == "" {
+		t.Skip("目录中没有百搭卡")
+	}
+	return wildcardID
+}
+
+func TestWildcardOnlyOffersOwnedColors(t *testing.T) {
+	state, _, err := Apply(newTestState(2), GameAction{Type: ActionStartGame})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wildcardID := firstWildcardCard(t, state)
+	playerID := state.Players[state.CurrentPlayerIndex].ID
+	player := NewGameLogic(&state).getPlayer(playerID)
+	for _, gem := range testGemOrder {
+		player.Gems[gem] = 10
+	}
+	player.Bonus[models.GemBlue] = 1
+	player.ReservedCards = append(player.ReservedCards, wildcardID)
+
+	buy := GameAction{Type: ActionBuyCard, PlayerID: playerID, BuyCard: &BuyCardCmd{CardID: wildcardID, AutoPay: true}}
+	bought, _, err := Apply(state, buy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bought.PendingDecisions) == 0 || bought.PendingDecisions[0].Type != models.DecisionWildcard {
+		t.Fatalf("购买百搭卡后应开启百搭颜色决策，实际 %+v", bought.PendingDecisions)
+	}
+	options := bought.PendingDecisions[0].Options
+	if len(options) != 1 || options[0].Gem != models.GemBlue {
+		t.Fatalf("百搭颜色只能选择已拥有的蓝色，实际 %+v", options)
+	}
+
+	resolve := func(gem models.GemType) error {
+		_, _, err := Apply(bought, GameAction{Type: ActionResolveDecision, PlayerID: playerID, ResolveDecision: &ResolveDecisionCmd{Choice: models.DecisionOption{Gem: gem}}})
+		return err
+	}
+	expectCode(t, resolve(models.GemRed), ErrInvalidChoice, "选择未拥有的红色")
+	expectCode(t, resolve(models.GemBlue), "", "选择已拥有的蓝色")
+}
+
+// 还没有任何有颜色的发展卡时不能购买百搭卡，合法动作与必选动作检查也不包含该购买
+func TestWildcardRequiresColoredCard(t *testing.T) {
+	gl := stuckState(t, models.StalematePass, nil)
+	state := gl.gameState
+	wildcardID := firstWildcardCard(t, *state)
+	player := &state.Players[state.CurrentPlayerIndex]
+	for _, gem := range testGemOrder {
+		player.Gems[gem] = 10
+	}
+	player.Bonus = map[models.GemType]int{}
+	player.ReservedCards = []string{wildcardID}
+
+	buy := GameAction{Type: ActionBuyCard, PlayerID: player.ID, BuyCard: &BuyCardCmd{CardID: wildcardID, AutoPay: true}}
+	_, _, err := Apply(*state, buy)
+	expectCode(t, err, ErrCardNotFound, "没有有颜色的发展卡时购买百搭卡")
+	if actions := gl.LegalActions(player.ID); len(actions) != 0 {
+		t.Fatalf("合法动作不应包含百搭卡的购买，实际 %d 个动作", len(actions))
+	}
+	if gl.hasMandatoryAction(player) {
+		t.Fatal("只能购买百搭卡时不应视为有必选动作")
+	}
+
+	player.Bonus[models.GemBlue] = 1
+	if !gl.hasMandatoryAction(player) {
+		t.Fatal("拥有蓝色发展卡后应可以购买百搭卡")
+	}
+	_, _, err = Apply(*state, buy)
+	expectCode(t, err, "", "拥有蓝色发展卡后购买百搭卡")
+}
+
+// 对局中替换卡牌目录不影响已开始的对局：贵族从开局时复制的数据中查找
+func TestNoblesSurviveCatalogSwap(t *testing.T) {
+	state, _, err := Apply(newTestState(4), GameAction{Type: ActionStartGame})
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := CurrentCatalog()
+	defer SetCatalog(original)
+	SetCatalog(&Catalog{})
+
+	gl := NewGameLogic(&state)
+	player := &state.Players[state.CurrentPlayerIndex]
+	nobleID := state.AvailableNobles[0]
+	if err := gl.handleNobleSelection(player.ID, nobleID); err != nil {
+		t.Fatalf("替换目录后获得贵族失败: %v", err)
+	}
+	if player.Points != state.NobleMap[nobleID].Points {
+		t.Fatalf("应获得贵族的 %d 分，实际 %d", state.NobleMap[nobleID].Points, player.Points)
+	}
+	if err := gl.CheckInvariants(); err != nil {
+		t.Fatalf("替换目录后状态校验失败: %v", err)
+	}
+}
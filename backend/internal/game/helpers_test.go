@@ -0,0 +1,109 @@
+package game
+
+import (
+	"encoding/json"
+	"math/rand"
+	"testing"
+	"time"
+
+	"splendor-duel-backend/internal/models"
+)
+
+// 丢弃宝石时按固定顺序选择，保证测试对局可复现
+var testGemOrder = []models.GemType{
+	models.GemWhite, models.GemBlue, models.GemGreen, models.GemRed, models.GemBlack, models.GemPearl, models.GemGold,
+}
+
+// newTestState 创建两名玩家、尚未开始的游戏状态
+func newTestState(seed int64) models.GameState {
+	state := NewGameState(DefaultRuleSet(), seed)
+	for i, id := range []string{"p1", "p2"} {
+		state.Players = append(state.Players, models.Player{
+			ID:               id,
+			Name:             "玩家" + id,
+			Gems:             make(map[models.GemType]int),
+			Bonus:            make(map[models.GemType]int),
+			ReservedCards:    []string{},
+			DevelopmentCards: []string{},
+			Nobles:           []string{},
+			IsHost:           i == 0,
+		})
+	}
+	return state
+}
+
+// newTestManager 创建包含一个等待开始的双人房间的管理器
+func newTestManager(seed int64) (*Manager, string) {
+	m := NewManager()
+	roomID := "room"
+	m.rooms[roomID] = &models.Room{ID: roomID, Name: "测试房间", GameState: newTestState(seed), CreatedAt: time.Now()}
+	return m, roomID
+}
+
+// nextTestAction 为当前应当行动的玩家选择一个合法动作：先结算决策与丢弃，否则从合法动作中随机选择
+func nextTestAction(t *testing.T, state *models.GameState, random *rand.Rand) GameAction {
+	t.Helper()
+	playerID := actingPlayer(state)
+
+	if len(state.PendingDecisions) > 0 {
+		options := state.PendingDecisions[0].Options
+		if len(options) == 0 {
+			t.Fatalf("决策 %s 没有可选项", state.PendingDecisions[0].Type)
+		}
+		choice := options[random.Intn(len(options))]
+		return GameAction{Type: ActionResolveDecision, PlayerID: playerID, ResolveDecision: &ResolveDecisionCmd{Choice: choice}}
+	}
+	if state.NeedsGemDiscard {
+		player := NewGameLogic(state).getPlayer(playerID)
+		for _, gem := range testGemOrder {
+			if player.Gems[gem] > 0 {
+				return GameAction{Type: ActionDiscardGems, PlayerID: playerID, DiscardGems: &DiscardGemsCmd{Gems: map[models.GemType]int{gem: 1}}}
+			}
+		}
+		t.Fatalf("玩家 %s 需要丢弃宝石但没有宝石", playerID)
+	}
+
+	actions := NewGameLogic(state).LegalActions(playerID)
+	if len(actions) == 0 {
+		t.Fatalf("玩家 %s 在阶段 %s 没有合法动作", playerID, state.TurnPhase)
+	}
+	return actions[random.Intn(len(actions))]
+}
+
+// 当前玩家的第一个拿取宝石动作（不会翻开牌堆，可以撤销）
+func takeGemsAction(t *testing.T, state *models.GameState) GameAction {
+	t.Helper()
+	playerID := state.Players[state.CurrentPlayerIndex].ID
+	for _, action := range NewGameLogic(state).LegalActions(playerID) {
+		if action.Type == ActionTakeGems {
+			return action
+		}
+	}
+	t.Fatalf("玩家 %s 没有可以拿取的宝石", playerID)
+	return GameAction{}
+}
+
+func marshalState(t *testing.T, state models.GameState) string {
+	t.Helper()
+	data, err := json.Marshal(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// playTestGame 通过管理器开始并自动进行一局游戏，直到结束或达到步数上限
+func playTestGame(t *testing.T, m *Manager, roomID string, seed int64) {
+	t.Helper()
+	if _, err := m.ApplyAction(roomID, GameAction{Type: ActionStartGame}); err != nil {
+		t.Fatalf("开始游戏失败: %v", err)
+	}
+	random := rand.New(rand.NewSource(seed))
+	room := m.rooms[roomID]
+	for step := 0; step < 500 && room.GameState.Status == models.GameStatusPlaying; step++ {
+		action := nextTestAction(t, &room.GameState, random)
+		if _, err := m.ApplyAction(roomID, action); err != nil {
+			t.Fatalf("第 %d 步 %s 被拒绝: %v", step+1, FormatMove(action), err)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+
+	"splendor-duel-backend/internal/models"
+)
+
+// 一局游戏的全部宝石token（按初始化顺序）：白蓝绿红黑各4个，珍珠2个，黄金3个，共25个
+var gemSupply = []struct {
+	Gem   models.GemType
+	Count int
+}{
+	{models.GemWhite, 4},
+	{models.GemBlue, 4},
+	{models.GemGreen, 4},
+	{models.GemRed, 4},
+	{models.GemBlack, 4},
+	{models.GemPearl, 2},
+	{models.GemGold, 3},
+}
+
+// CheckInvariants 校验游戏状态的守恒关系，返回全部违反项（errors.Join），状态一致时返回 nil：
+// 每种token在版图、袋子与玩家手中的总数不变；特权指示物总数不变；
+// 每张发展卡恰好位于牌堆/翻开/保留/已购买之一；bonus、分数、皇冠与已获得的发展卡和贵族一致
+func (gl *GameLogic) CheckInvariants() error {
+	state := gl.gameState
+	if state.Status == models.GameStatusWaiting {
+		return nil
+	}
+
+	var errs []error
+	fail := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	// 宝石token守恒
+	tokens := make(map[models.GemType]int)
+	for _, row := range state.GemBoard {
+		for _, gem := range row {
+			if gem != "" {
+				tokens[gem]++
+			}
+		}
+	}
+	for _, gem := range state.GemBag {
+		tokens[gem]++
+	}
+	for _, player := range state.Players {
+		for gem, count := range player.Gems {
+			if count < 0 {
+				fail("玩家 %s 的 %s 数量为负数: %d", player.ID, gem, count)
+			}
+			tokens[gem] += count
+		}
+	}
+	for _, supply := range gemSupply {
+		if tokens[supply.Gem] != supply.Count {
+			fail("%s token 总数应为 %d，实际 %d", supply.Gem, supply.Count, tokens[supply.Gem])
+		}
+		delete(tokens, supply.Gem)
+	}
+	for gem, count := range tokens {
+		if count != 0 {
+			fail("出现未知的 token %s: %d 个", gem, count)
+		}
+	}
+
+	// 特权指示物守恒
+	privileges := state.AvailablePrivilegeTokens
+	if privileges < 0 {
+		fail("可用特权指示物为负数: %d", privileges)
+	}
+	for _, player := range state.Players {
+		if player.PrivilegeTokens < 0 {
+			fail("玩家 %s 的特权指示物为负数: %d", player.ID, player.PrivilegeTokens)
+		}
+		privileges += player.PrivilegeTokens
+	}
+	if max := gl.rules().MaxPrivileges; privileges != max {
+		fail("特权指示物总数应为 %d，实际 %d", max, privileges)
+	}
+
+	// 发展卡位置：每张卡恰好出现在一个位置
+	locations := make(map[string][]string)
+	decks := map[models.CardLevel][]string{
+		models.Level1: state.Level1Deck,
+		models.Level2: state.Level2Deck,
+		models.Level3: state.Level3Deck,
+	}
+	for level := models.Level1; level <= models.Level3; level++ {
+		for _, cardID := range decks[level] {
+			locations[cardID] = append(locations[cardID], fmt.Sprintf("等级%d牌堆", level))
+			if card, ok := state.CardMap[cardID]; ok && card.Level != level {
+				fail("发展卡 %s 的等级为 %d，却在等级%d牌堆中", cardID, card.Level, level)
+			}
+		}
+		if state.UnflippedCards[level] != len(decks[level]) {
+			fail("等级%d未翻开数量为 %d，牌堆实际 %d 张", level, state.UnflippedCards[level], len(decks[level]))
+		}
+		for _, cardID := range state.FlippedCards[level] {
+			locations[cardID] = append(locations[cardID], fmt.Sprintf("等级%d翻开区", level))
+			if card, ok := state.CardMap[cardID]; ok && card.Level != level {
+				fail("发展卡 %s 的等级为 %d，却在等级%d翻开区中", cardID, card.Level, level)
+			}
+		}
+	}
+	for _, player := range state.Players {
+		for _, cardID := range player.ReservedCards {
+			locations[cardID] = append(locations[cardID], "玩家"+player.ID+"保留区")
+		}
+		for _, cardID := range player.BlindReservedCards {
+			reserved := false
+			for _, reservedID := range player.ReservedCards {
+				reserved = reserved || reservedID == cardID
+			}
+			if !reserved {
+				fail("玩家 %s 盲抽保留的发展卡 %s 不在保留区", player.ID, cardID)
+			}
+		}
+		for _, cardID := range player.DevelopmentCards {
+			locations[cardID] = append(locations[cardID], "玩家"+player.ID+"已购买")
+		}
+	}
+	for cardID := range state.CardMap {
+		switch places := locations[cardID]; len(places) {
+		case 0:
+			fail("发展卡 %s 不在任何位置", cardID)
+		case 1:
+		default:
+			fail("发展卡 %s 同时出现在: %v", cardID, places)
+		}
+		delete(locations, cardID)
+	}
+	for cardID, places := range locations {
+		fail("未知的发展卡 %s 出现在: %v", cardID, places)
+	}
+
+	// 贵族：每位贵族最多出现一次
+	nobles := make(map[string]int)
+	for _, nobleID := range state.AvailableNobles {
+		nobles[nobleID]++
+	}
+
+	// 玩家的bonus、分数、皇冠与已获得的发展卡和贵族一致
+	for _, player := range state.Players {
+		bonus := make(map[models.GemType]int)
+		points, crowns := 0, 0
+		for _, cardID := range player.DevelopmentCards {
+			card, ok := state.CardMap[cardID]
+			if !ok {
+				continue
+			}
+			bonus[card.Bonus] += cardBonusCount(card)
+			points += card.Points
+			crowns += card.Crowns
+		}
+		for _, nobleID := range player.Nobles {
+			nobles[nobleID]++
+			noble, ok := state.NobleMap[nobleID]
+			if !ok {
+				fail("玩家 %s 拥有未知的贵族 %s", player.ID, nobleID)
+				continue
+			}
+			points += noble.Points
+		}
+
+		for gem, count := range player.Bonus {
+			if count != bonus[gem] {
+				fail("玩家 %s 的 %s bonus 为 %d，按已购买的发展卡应为 %d", player.ID, gem, count, bonus[gem])
+			}
+		}
+		for gem, count := range bonus {
+			if _, ok := player.Bonus[gem]; !ok && count != 0 {
+				fail("玩家 %s 缺少 %s bonus，按已购买的发展卡应为 %d", player.ID, gem, count)
+			}
+		}
+		if player.Points != points {
+			fail("玩家 %s 的分数为 %d，按发展卡与贵族应为 %d", player.ID, player.Points, points)
+		}
+		if player.Crowns != crowns {
+			fail("玩家 %s 的皇冠为 %d，按发展卡应为 %d", player.ID, player.Crowns, crowns)
+		}
+	}
+	for nobleID, count := range nobles {
+		if count > 1 {
+			fail("贵族 %s 出现了 %d 次", nobleID, count)
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,146 @@
+package game
+
+import "splendor-duel-backend/internal/models"
+
+// 拿取宝石时沿直线延伸的方向（只取正向，避免重复）
+var lineDirections = []models.Coord{{X: 0, Y: 1}, {X: 1, Y: 0}, {X: 1, Y: 1}, {X: 1, Y: -1}}
+
+// LegalActions 列出玩家在当前回合阶段可以执行的全部可选动作与必选动作
+// 不是该玩家的回合、或当前阶段不允许可选/必选动作时返回空列表
+func (gl *GameLogic) LegalActions(playerID string) []GameAction {
+	if gl.gameState.Status != models.GameStatusPlaying {
+		return nil
+	}
+	playerIndex := gl.getPlayerIndex(playerID)
+	if playerIndex == -1 || gl.gameState.CurrentPlayerIndex != playerIndex {
+		return nil
+	}
+	player := &gl.gameState.Players[playerIndex]
+
+	var actions []GameAction
+	switch gl.gameState.TurnPhase {
+	case models.PhaseOptional:
+		actions = append(actions, gl.legalPrivilegeSpends(player)...)
+		if len(gl.gameState.GemBag) > 0 {
+			actions = append(actions, GameAction{Type: ActionRefillBoard, PlayerID: playerID})
+		}
+		actions = append(actions, gl.legalMandatoryActions(player)...)
+	case models.PhaseMandatory:
+		actions = append(actions, gl.legalMandatoryActions(player)...)
+	}
+	return actions
+}
+
+// 必选动作：拿取宝石、保留发展卡、购买发展卡
+func (gl *GameLogic) legalMandatoryActions(player *models.Player) []GameAction {
+	var actions []GameAction
+
+	for _, line := range gl.takeableGemLines() {
+		actions = append(actions, GameAction{Type: ActionTakeGems, PlayerID: player.ID, TakeGems: &TakeGemsCmd{Positions: line}})
+	}
+
+	if len(player.ReservedCards) < gl.rules().MaxReserved {
+		golds := gl.boardPositions(func(gem models.GemType) bool { return gem == models.GemGold })
+		for _, gold := range golds {
+			for level := models.Level1; level <= models.Level3; level++ {
+				for _, cardID := range gl.gameState.FlippedCards[level] {
+					actions = append(actions, GameAction{Type: ActionReserveCard, PlayerID: player.ID, ReserveCard: &ReserveCardCmd{CardID: cardID, Gold: gold}})
+				}
+			}
+			for level := models.Level1; level <= models.Level3; level++ {
+				if gl.gameState.UnflippedCards[level] > 0 {
+					actions = append(actions, GameAction{Type: ActionReserveCard, PlayerID: player.ID, ReserveCard: &ReserveCardCmd{DeckLevel: level, Gold: gold}})
+				}
+			}
+		}
+	}
+
+	var buyable []string
+	for level := models.Level1; level <= models.Level3; level++ {
+		buyable = append(buyable, gl.gameState.FlippedCards[level]...)
+	}
+	buyable = append(buyable, player.ReservedCards...)
+	for _, cardID := range buyable {
+		if !gl.isCardBuyable(player, cardID) {
+			continue
+		}
+		card := gl.gameState.CardMap[cardID]
+		required := gl.calculateRequiredGems(&DevelopmentCardData{Cost: card.Cost}, player)
+		for _, plan := range rankPaymentPlans(player, paymentPlans(player, required)) {
+			actions = append(actions, GameAction{Type: ActionBuyCard, PlayerID: player.ID, BuyCard: &BuyCardCmd{CardID: cardID, Payment: plan}})
+		}
+	}
+
+	return actions
+}
+
+// 花费特权：每个特权拿取版图上任意一个非黄金宝石
+func (gl *GameLogic) legalPrivilegeSpends(player *models.Player) []GameAction {
+	cells := gl.boardPositions(func(gem models.GemType) bool { return gem != models.GemGold })
+
+	var actions []GameAction
+	for count := 1; count <= player.PrivilegeTokens && count <= len(cells); count++ {
+		for _, positions := range coordCombinations(cells, count) {
+			actions = append(actions, GameAction{Type: ActionSpendPrivilege, PlayerID: player.ID, SpendPrivilege: &SpendPrivilegeCmd{Positions: positions}})
+		}
+	}
+	return actions
+}
+
+// 版图上所有可拿取的1-3个连续且同一直线上的非黄金宝石组合
+func (gl *GameLogic) takeableGemLines() [][]models.Coord {
+	takeable := func(c models.Coord) bool {
+		if c.X < 0 || c.X >= len(gl.gameState.GemBoard) || c.Y < 0 || c.Y >= len(gl.gameState.GemBoard[c.X]) {
+			return false
+		}
+		gem := gl.gameState.GemBoard[c.X][c.Y]
+		return gem != "" && gem != models.GemGold
+	}
+
+	var lines [][]models.Coord
+	for _, start := range gl.boardPositions(func(gem models.GemType) bool { return gem != models.GemGold }) {
+		lines = append(lines, []models.Coord{start})
+		for _, dir := range lineDirections {
+			line := []models.Coord{start}
+			for step := 1; step < 3; step++ {
+				next := models.Coord{X: start.X + dir.X*step, Y: start.Y + dir.Y*step}
+				if !takeable(next) {
+					break
+				}
+				line = append(line, next)
+				lines = append(lines, append([]models.Coord(nil), line...))
+			}
+		}
+	}
+	return lines
+}
+
+// 按行列顺序列出版图上满足条件的非空位置
+func (gl *GameLogic) boardPositions(match func(models.GemType) bool) []models.Coord {
+	var positions []models.Coord
+	for x, row := range gl.gameState.GemBoard {
+		for y, gem := range row {
+			if gem != "" && match(gem) {
+				positions = append(positions, models.Coord{X: x, Y: y})
+			}
+		}
+	}
+	return positions
+}
+
+// 从坐标列表中选取 k 个的全部组合
+func coordCombinations(coords []models.Coord, k int) [][]models.Coord {
+	var result [][]models.Coord
+	var pick func(start int, chosen []models.Coord)
+	pick = func(start int, chosen []models.Coord) {
+		if len(chosen) == k {
+			result = append(result, append([]models.Coord(nil), chosen...))
+			return
+		}
+		for i := start; i <= len(coords)-(k-len(chosen)); i++ {
+			pick(i+1, append(chosen, coords[i]))
+		}
+	}
+	pick(0, nil)
+	return result
+}
@@ -1,12 +1,19 @@
 package game
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
 	"splendor-duel-backend/internal/models"
+	"splendor-duel-backend/internal/session"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -16,13 +23,124 @@ import (
 type Manager struct {
 	rooms map[string]*models.Room
 	mutex sync.RWMutex
+
+	// 领域事件订阅者
+	subscribers []EventHandler
+	subMutex    sync.RWMutex
+
+	// 调试模式：状态校验失败时冻结房间并导出现场
+	debug   bool
+	dumpDir string
+	frozen  map[string]string // 房间ID -> 导出文件路径
+
+	// 悔棋：每个房间可撤销动作的快照，以及等待对手回应的请求方
+	undo         map[string][]undoSnapshot
+	undoRequests map[string]string
+
+	// 公平性记录：每个房间从开始游戏起执行的动作及其公开结果
+	records map[string]*GameRecord
+
+	// 持久化存储：房间、操作记录与聊天
+	store Store
+	// 每个房间已保存的操作记录条数，悔棋快照记下动作之前的条数，撤销时据此截断
+	historyLength map[string]int
+
+	// 会话令牌签名器：玩家身份只来自创建/加入房间时签发的令牌
+	sessions *session.Signer
 }
 
+// 房间创建后保留的时长，过期后清理
+const roomLifetime = 24 * time.Hour
+
+// EventHandler 接收房间内动作产生的领域事件
+type EventHandler func(roomID string, events []Event)
+
 // NewManager 创建新的游戏管理器
 func NewManager() *Manager {
 	return &Manager{
-		rooms: make(map[string]*models.Room),
+		rooms:         make(map[string]*models.Room),
+		frozen:        make(map[string]string),
+		undo:          make(map[string][]undoSnapshot),
+		undoRequests:  make(map[string]string),
+		records:       make(map[string]*GameRecord),
+		store:         NewMemoryStore(),
+		historyLength: make(map[string]int),
+		sessions:      session.NewRandomSigner(),
+	}
+}
+
+// SetSessionSecret 设置会话令牌的签名密钥；未设置时使用随机密钥，服务重启后令牌失效
+func (m *Manager) SetSessionSecret(secret []byte) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.sessions = session.NewSigner(secret)
+}
+
+// Authenticate 校验房间的会话令牌，返回令牌绑定且仍在房间内的玩家ID
+func (m *Manager) Authenticate(roomID, token string) (string, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	room, exists := m.rooms[roomID]
+	if !exists {
+		return "", NewActionError(ErrRoomNotFound, "房间不存在")
+	}
+	playerID, err := m.sessions.Verify(token, roomID)
+	if err != nil {
+		return "", NewActionError(ErrUnauthorized, "会话无效，请重新加入房间")
 	}
+	for _, player := range room.GameState.Players {
+		if player.ID == playerID {
+			return playerID, nil
+		}
+	}
+	return "", NewActionError(ErrUnauthorized, "会话无效，请重新加入房间")
+}
+
+// 从 Authorization: Bearer 头读取会话令牌并校验
+// 不接受查询参数中的令牌：访问日志会记录完整的查询字符串
+func (m *Manager) sessionPlayer(c *gin.Context, roomID string) (string, error) {
+	token, _ := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	return m.Authenticate(roomID, token)
+}
+
+// SetDebug 开启或关闭调试模式；开启后动作导致状态校验失败时，房间被冻结并把现场导出到 dumpDir
+func (m *Manager) SetDebug(enabled bool, dumpDir string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.debug = enabled
+	m.dumpDir = dumpDir
+}
+
+// NewGameState 创建等待玩家加入的游戏状态；棋谱重放时用同样的方式重建开局前的状态
+func NewGameState(rules models.RuleSet, seed int64) models.GameState {
+	gameState := models.GameState{
+		Status:                   models.GameStatusWaiting,
+		CurrentPlayerIndex:       0,
+		TurnNumber:               0,
+		Players:                  []models.Player{},
+		Winner:                   "",
+		GemBoard:                 make([][]models.GemType, 5),
+		GemBag:                   []models.GemType{},
+		AvailablePrivilegeTokens: rules.MaxPrivileges,
+		UnflippedCards:           map[models.CardLevel]int{},
+		FlippedCards:             map[models.CardLevel][]string{},
+		AvailableNobles:          CurrentCatalog().NobleIDs(),
+		ExtraTurns:               make(map[string]int),
+		CardToRefill:             models.PendingRefill{Level: 0, Index: 0},
+		NeedsGemDiscard:          false,
+		GemDiscardTarget:         rules.MaxTokens,
+		GemDiscardPlayerID:       "",
+		Rules:                    rules,
+		Seed:                     seed,
+		CreatedAt:                time.Now(),
+	}
+
+	// 初始化宝石版图（即使在等待状态也要显示）
+	gl := NewGameLogic(&gameState)
+	gl.initializeGemBoard()
+	gl.initializeDevelopmentCards()
+	return gameState
 }
 
 // CreateRoom 创建房间
@@ -50,6 +168,16 @@ func (m *Manager) CreateRoom(c *gin.Context) {
 	}
 	m.mutex.RUnlock()
 
+	// 选择规则预设
+	rules, ok := RuleSetByName(req.RuleSet)
+	if !ok {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "未知的规则预设: " + req.RuleSet,
+		})
+		return
+	}
+
 	// 生成房间ID和玩家ID
 	roomID := uuid.New().String()
 	playerID := uuid.New().String()
@@ -70,31 +198,19 @@ func (m *Manager) CreateRoom(c *gin.Context) {
 		LastActive:        time.Now(),
 	}
 
-	// 创建游戏状态
-	gameState := models.GameState{
-		Status:                   models.GameStatusWaiting,
-		CurrentPlayerIndex:       0,
-		TurnNumber:               0,
-		Players:                  []models.Player{player},
-		Winner:                   "",
-		GemBoard:                 make([][]models.GemType, 5),
-		GemBag:                   []models.GemType{},
-		AvailablePrivilegeTokens: 3,
-		UnflippedCards:           map[models.CardLevel]int{},
-		FlippedCards:             map[models.CardLevel][]string{},
-		AvailableNobles:          []string{"noble1", "noble2", "noble3", "noble4"},
-		ExtraTurns:               make(map[string]int),
-		CardToRefill:             models.PendingRefill{Level: 0, Index: 0},
-		NeedsGemDiscard:          false,
-		GemDiscardTarget:         10,
-		GemDiscardPlayerID:       "",
-		CreatedAt:                time.Now(),
+	// 创建游戏状态：种子总是由服务端生成，客户端无法指定（否则可以预先算出版图与牌堆顺序）
+	// 复现对局时通过 NewGameState 或 cmd/replay 注入种子
+	seed, err := NewSeed()
+	if err != nil {
+		log.Printf("创建房间失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "创建房间失败",
+		})
+		return
 	}
-	
-	// 初始化宝石版图（即使在等待状态也要显示）
-	gl := NewGameLogic(&gameState, m)
-	gl.initializeGemBoard()
-	gl.initializeDevelopmentCards()
+	gameState := NewGameState(rules, seed)
+	gameState.Players = []models.Player{player}
 
 	// 创建房间
 	room := &models.Room{
@@ -108,15 +224,18 @@ func (m *Manager) CreateRoom(c *gin.Context) {
 	// 保存房间
 	m.mutex.Lock()
 	m.rooms[roomID] = room
+	m.persist(roomID)
+	token := m.sessions.Issue(roomID, playerID)
 	m.mutex.Unlock()
 
-	log.Printf("创建房间: %s (ID: %s), 玩家: %s", req.RoomName, roomID, req.PlayerName)
+	log.Printf("创建房间: %s (ID: %s), 玩家: %s, 规则: %s", req.RoomName, roomID, req.PlayerName, rules.Name)
 
 	c.JSON(http.StatusOK, models.APIResponse{
 		Success: true,
 		Data: models.CreateRoomResponse{
-			Room:     *room,
-			PlayerID: playerID,
+			Room:         room.ViewFor(playerID),
+			PlayerID:     playerID,
+			SessionToken: token,
 		},
 	})
 }
@@ -194,6 +313,9 @@ func (m *Manager) JoinRoom(c *gin.Context) {
 	m.mutex.Lock()
 	targetRoom.GameState.Players = append(targetRoom.GameState.Players, player)
 	targetRoom.UpdatedAt = time.Now()
+	m.persist(targetRoom.ID)
+	view := targetRoom.ViewFor(playerID)
+	token := m.sessions.Issue(targetRoom.ID, playerID)
 	m.mutex.Unlock()
 
 	log.Printf("玩家 %s 加入房间: %s", req.PlayerName, req.RoomName)
@@ -201,20 +323,130 @@ func (m *Manager) JoinRoom(c *gin.Context) {
 	c.JSON(http.StatusOK, models.APIResponse{
 		Success: true,
 		Data: models.JoinRoomResponse{
-			Room:     *targetRoom,
-			PlayerID: playerID,
+			Room:         view,
+			PlayerID:     playerID,
+			SessionToken: token,
 		},
 	})
 }
 
-// GetRoomInfo 获取房间信息
+// GetRoomInfo 获取房间信息（携带有效会话令牌时为该玩家视角，否则为观战视角）
 func (m *Manager) GetRoomInfo(c *gin.Context) {
 	roomID := c.Param("roomId")
+	playerID, _ := m.sessionPlayer(c, roomID)
+	room, exists := m.RoomView(roomID, playerID)
+	if !exists {
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Success: false,
+			Message: "房间不存在",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    room,
+	})
+}
+
+// 需要会话的接口：令牌无效时回复 401
+func (m *Manager) requireSession(c *gin.Context, roomID string) (string, bool) {
+	playerID, err := m.sessionPlayer(c, roomID)
+	if err != nil {
+		status := http.StatusUnauthorized
+		if CodeOf(err, ErrInternal) == ErrRoomNotFound {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, models.APIResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return "", false
+	}
+	return playerID, true
+}
+
+// CheckSession 校验会话令牌，有效时返回令牌绑定的玩家ID，无效时回复 401，房间不存在时回复 404
+func (m *Manager) CheckSession(c *gin.Context) {
+	playerID, ok := m.requireSession(c, c.Param("roomId"))
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    gin.H{"playerId": playerID},
+	})
+}
+
+// GetRuleSets 列出可在创建房间时选择的规则预设
+func (m *Manager) GetRuleSets(c *gin.Context) {
+	ruleSets := []models.RuleSet{}
+	for _, name := range RuleSetNames() {
+		rules, _ := RuleSetByName(name)
+		ruleSets = append(ruleSets, rules)
+	}
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    ruleSets,
+	})
+}
+
+// GetCatalog 获取当前使用的发展卡与贵族目录
+func (m *Manager) GetCatalog(c *gin.Context) {
+	catalog := CurrentCatalog()
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data: gin.H{
+			"cards":  catalog.DevelopmentCards(),
+			"nobles": catalog.Nobles,
+		},
+	})
+}
+
+// GetLegalActions 获取玩家在当前局面下的全部合法动作
+func (m *Manager) GetLegalActions(c *gin.Context) {
+	roomID := c.Param("roomId")
+	playerID, ok := m.requireSession(c, roomID)
+	if !ok {
+		return
+	}
 
 	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
 	room, exists := m.rooms[roomID]
-	m.mutex.RUnlock()
+	if !exists {
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Success: false,
+			Message: "房间不存在",
+		})
+		return
+	}
+
+	gl := NewGameLogic(&room.GameState)
+	actions := gl.LegalActions(playerID)
+	if actions == nil {
+		actions = []GameAction{}
+	}
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    actions,
+	})
+}
 
+// GetPaymentPlans 获取玩家购买指定卡牌的推荐支付方案
+func (m *Manager) GetPaymentPlans(c *gin.Context) {
+	roomID := c.Param("roomId")
+	cardID := c.Query("cardId")
+	playerID, ok := m.requireSession(c, roomID)
+	if !ok {
+		return
+	}
+
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	room, exists := m.rooms[roomID]
 	if !exists {
 		c.JSON(http.StatusNotFound, models.APIResponse{
 			Success: false,
@@ -223,12 +455,63 @@ func (m *Manager) GetRoomInfo(c *gin.Context) {
 		return
 	}
 
+	gl := NewGameLogic(&room.GameState)
+	plans, err := gl.SuggestPaymentPlans(playerID, cardID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+	if plans == nil {
+		plans = []map[models.GemType]int{}
+	}
 	c.JSON(http.StatusOK, models.APIResponse{
 		Success: true,
-		Data:    room,
+		Data:    plans,
 	})
 }
 
+// GetGameRecord 获取房间的公平性记录（游戏结束后包含种子，可交给 cmd/verify 校验）
+func (m *Manager) GetGameRecord(c *gin.Context) {
+	record, err := m.GameRecord(c.Param("roomId"))
+	if err != nil {
+		status := http.StatusConflict
+		if CodeOf(err, ErrInternal) == ErrRoomNotFound {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, models.APIResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    record,
+	})
+}
+
+// GetNotation 以文本棋谱导出房间的对局（游戏结束后包含种子，可用 cmd/replay 重放）
+func (m *Manager) GetNotation(c *gin.Context) {
+	text, err := m.ExportNotation(c.Param("roomId"))
+	if err != nil {
+		status := http.StatusConflict
+		if CodeOf(err, ErrInternal) == ErrRoomNotFound {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, models.APIResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.String(http.StatusOK, text)
+}
+
 // GetRoom 获取房间（内部使用）
 func (m *Manager) GetRoom(roomID string) *models.Room {
 	m.mutex.RLock()
@@ -236,6 +519,18 @@ func (m *Manager) GetRoom(roomID string) *models.Room {
 	return m.rooms[roomID]
 }
 
+// RoomView 返回房间在指定玩家视角下的副本，见 models.GameState.ViewFor
+func (m *Manager) RoomView(roomID, playerID string) (models.Room, bool) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	room, exists := m.rooms[roomID]
+	if !exists {
+		return models.Room{}, false
+	}
+	return room.ViewFor(playerID), true
+}
+
 // UpdateRoom 更新房间（内部使用）
 func (m *Manager) UpdateRoom(roomID string, updateFunc func(*models.Room)) {
 	m.mutex.Lock()
@@ -244,7 +539,124 @@ func (m *Manager) UpdateRoom(roomID string, updateFunc func(*models.Room)) {
 	if room, exists := m.rooms[roomID]; exists {
 		updateFunc(room)
 		room.UpdatedAt = time.Now()
+		m.persist(roomID)
+	}
+}
+
+// ApplyAction 在房间的游戏状态上执行动作，成功后把产生的事件发布给订阅者
+func (m *Manager) ApplyAction(roomID string, action GameAction) ([]Event, error) {
+	m.mutex.Lock()
+	room, exists := m.rooms[roomID]
+	if !exists {
+		m.mutex.Unlock()
+		return nil, NewActionError(ErrRoomNotFound, "房间不存在")
+	}
+	if _, frozen := m.frozen[roomID]; frozen {
+		m.mutex.Unlock()
+		return nil, NewActionError(ErrRoomFrozen, "房间状态异常，已冻结等待排查")
+	}
+
+	next, events, err := Apply(room.GameState, action)
+	if err != nil {
+		m.mutex.Unlock()
+		return nil, err
+	}
+	if action.Type == ActionStartGame {
+		next.StartedAt = time.Now()
+	}
+	if violation := NewGameLogic(&next).CheckInvariants(); violation != nil {
+		m.reportViolation(roomID, room.GameState, next, action, violation)
+	}
+	m.recordUndo(roomID, &room.GameState, &next, action)
+	m.recordStep(roomID, &room.GameState, &next, action, events)
+	room.GameState = next
+	room.UpdatedAt = time.Now()
+	m.persist(roomID)
+	m.mutex.Unlock()
+
+	m.publish(roomID, events)
+	return events, nil
+}
+
+// 状态校验失败：记录日志；调试模式下冻结房间并导出动作前后的状态与动作本身（调用方持有 m.mutex）
+func (m *Manager) reportViolation(roomID string, previous, next models.GameState, action GameAction, violation error) {
+	log.Printf("房间 %s 执行动作 %s 后状态校验失败:\n%v", roomID, action.Type, violation)
+	if !m.debug {
+		return
+	}
+
+	violations := []string{violation.Error()}
+	if joined, ok := violation.(interface{ Unwrap() []error }); ok {
+		violations = violations[:0]
+		for _, err := range joined.Unwrap() {
+			violations = append(violations, err.Error())
+		}
+	}
+	dump := struct {
+		RoomID        string           `json:"roomId"`
+		Time          time.Time        `json:"time"`
+		Action        GameAction       `json:"action"`
+		Violations    []string         `json:"violations"`
+		PreviousState models.GameState `json:"previousState"`
+		State         models.GameState `json:"state"`
+	}{roomID, time.Now(), action, violations, previous, next}
+
+	path, err := writeDump(m.dumpDir, fmt.Sprintf("%s-%d.json", roomID, dump.Time.UnixNano()), dump)
+	if err != nil {
+		log.Printf("导出房间 %s 的现场失败: %v", roomID, err)
+	} else {
+		log.Printf("房间 %s 已冻结，现场已导出到 %s", roomID, path)
 	}
+	m.frozen[roomID] = path
+}
+
+// 把现场以 JSON 写入导出目录，返回文件路径
+func writeDump(dir, name string, dump any) (string, error) {
+	if dir == "" {
+		return "", errors.New("未设置导出目录")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(dump, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, name)
+	return path, os.WriteFile(path, data, 0o644)
+}
+
+// Subscribe 订阅所有房间的领域事件
+func (m *Manager) Subscribe(handler EventHandler) {
+	m.subMutex.Lock()
+	defer m.subMutex.Unlock()
+	m.subscribers = append(m.subscribers, handler)
+}
+
+// 按订阅顺序同步通知订阅者
+func (m *Manager) publish(roomID string, events []Event) {
+	m.subMutex.RLock()
+	subscribers := append([]EventHandler(nil), m.subscribers...)
+	m.subMutex.RUnlock()
+
+	for _, handler := range subscribers {
+		handler(roomID, events)
+	}
+}
+
+// PlayerName 获取房间内玩家的名称
+func (m *Manager) PlayerName(roomID, playerID string) string {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	if room, exists := m.rooms[roomID]; exists {
+		for _, player := range room.GameState.Players {
+			if player.ID == playerID {
+				return player.Name
+			}
+		}
+	}
+	return ""
 }
 
 // CleanupExpiredRooms 清理过期房间
@@ -257,13 +669,21 @@ func (m *Manager) CleanupExpiredRooms() {
 
 	for roomID, room := range m.rooms {
 		// 检查房间是否超过24小时
-		if now.Sub(room.CreatedAt) > 24*time.Hour {
+		if now.Sub(room.CreatedAt) > roomLifetime {
 			expiredRooms = append(expiredRooms, roomID)
 		}
 	}
 
 	for _, roomID := range expiredRooms {
 		delete(m.rooms, roomID)
+		delete(m.frozen, roomID)
+		delete(m.undo, roomID)
+		delete(m.undoRequests, roomID)
+		delete(m.records, roomID)
+		delete(m.historyLength, roomID)
+		if err := m.store.DeleteRoom(roomID); err != nil {
+			log.Printf("删除房间 %s 的存储失败: %v", roomID, err)
+		}
 		log.Printf("清理过期房间: %s", roomID)
 	}
 }
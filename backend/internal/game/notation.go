@@ -0,0 +1,570 @@
+package game
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"splendor-duel-backend/internal/models"
+)
+
+// 文本棋谱：先是 [标签 "值"] 形式的头部（玩家、规则、卡牌目录指纹、种子与初始布局），
+// 空行之后每行一个动作，以 ; 开头的行为注释。动作的执行者由重放时的状态决定（当前玩家、
+// 待决策玩家或需要丢弃宝石的玩家），因此动作行不写玩家：
+//
+//	T 2,2 2,3 2,4                  拿取宝石（版图坐标 x,y）
+//	P 1,1 1,2                      花费特权指示物拿取宝石
+//	F                              补充版图
+//	B h3 pay red2 gold1            购买发展卡；免费时省略 pay，with 后为随购买预先作答的决策
+//	B a2 pay white3 with wildcard=blue extra_token=2,3
+//	R h3 gold 0,4                  保留翻开的发展卡并拿取该位置的黄金
+//	R deck2 gold 0,4               从等级2牌堆盲抽保留
+//	D red1 blue2                   丢弃超出上限的宝石
+//	C 2,3 / C red / C noble2       回答当前决策：版图位置、宝石颜色或贵族ID
+
+// Notation 解析后的棋谱
+type Notation struct {
+	Tags  map[string]string // 头部标签
+	Moves []NotationMove    // 开始游戏之后按顺序执行的动作
+}
+
+// NotationMove 棋谱中的一个动作行
+type NotationMove struct {
+	Line   int        // 在文本中的行号（从1开始）
+	Text   string     // 原始文本
+	Action GameAction // 解析得到的动作（PlayerID 在重放时确定）
+}
+
+// 头部标签的输出顺序
+var notationTagOrder = []string{
+	"Event", "Room", "Date", "Player1", "Player1Id", "Player2", "Player2Id",
+	"Rules", "Catalog", "SeedCommitment", "Seed", "First", "Board", "Level1", "Level2", "Level3", "Result",
+}
+
+// ExportNotation 把房间的对局导出为文本棋谱；种子只在游戏结束后写入，未结束的对局无法重放
+// 状态与记录在同一次加锁中读取，头部、结果与动作列表对应同一时刻
+func (m *Manager) ExportNotation(roomID string) (string, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	record, err := m.gameRecordLocked(roomID)
+	if err != nil {
+		return "", err
+	}
+	room := m.rooms[roomID]
+	return FormatNotation(room.Name, record, cloneGameState(&room.GameState)), nil
+}
+
+// FormatNotation 由公平性记录与当前状态生成文本棋谱
+func FormatNotation(roomName string, record GameRecord, state models.GameState) string {
+	tags := map[string]string{
+		"Event":          "Splendor Duel",
+		"Room":           roomName,
+		"Rules":          record.Initial.Rules.Name,
+		"Catalog":        state.CatalogVersion,
+		"SeedCommitment": record.SeedCommitment,
+		"Result":         notationResult(state),
+	}
+	if !state.StartedAt.IsZero() {
+		tags["Date"] = state.StartedAt.Format("2006.01.02")
+	}
+	for i, player := range record.Initial.Players {
+		tags[fmt.Sprintf("Player%d", i+1)] = player.Name
+		tags[fmt.Sprintf("Player%dId", i+1)] = player.ID
+	}
+	if record.Seed != nil {
+		tags["Seed"] = strconv.FormatInt(*record.Seed, 10)
+	}
+	if len(record.Steps) > 0 {
+		layout := record.Steps[0].Observed
+		tags["First"] = strconv.Itoa(layout.CurrentPlayerIndex + 1)
+		tags["Board"] = formatBoard(layout.GemBoard)
+		for level := models.Level1; level <= models.Level3; level++ {
+			tags[fmt.Sprintf("Level%d", level)] = strings.Join(layout.FlippedCards[level], " ")
+		}
+	}
+
+	var b strings.Builder
+	for _, name := range notationTagOrder {
+		if value, ok := tags[name]; ok {
+			fmt.Fprintf(&b, "[%s %q]\n", name, value)
+		}
+	}
+	b.WriteString("\n")
+
+	// 执行者变化时写一行注释，方便阅读
+	actor := -1
+	for i, step := range record.Steps {
+		if i == 0 {
+			continue
+		}
+		if index := playerIndexOf(record.Initial.Players, step.Action.PlayerID); index != actor {
+			actor = index
+			if index >= 0 {
+				fmt.Fprintf(&b, "; %s\n", record.Initial.Players[index].Name)
+			}
+		}
+		b.WriteString(FormatMove(step.Action))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func notationResult(state models.GameState) string {
+	if state.Status != models.GameStatusFinished {
+		return "*"
+	}
+	switch playerIndexOf(state.Players, state.Winner) {
+	case 0:
+		return "1-0"
+	case 1:
+		return "0-1"
+	}
+	return "1/2-1/2"
+}
+
+func playerIndexOf(players []models.Player, playerID string) int {
+	for i, player := range players {
+		if player.ID == playerID {
+			return i
+		}
+	}
+	return -1
+}
+
+// 版图按行写出，行之间用 / 分隔，空位为 .
+func formatBoard(board [][]models.GemType) string {
+	rows := make([]string, len(board))
+	for x, row := range board {
+		cells := make([]string, len(row))
+		for y, gem := range row {
+			cells[y] = string(gem)
+			if gem == "" {
+				cells[y] = "."
+			}
+		}
+		rows[x] = strings.Join(cells, " ")
+	}
+	return strings.Join(rows, "/")
+}
+
+func parseBoard(text string) ([][]models.GemType, error) {
+	rows := strings.Split(text, "/")
+	board := make([][]models.GemType, len(rows))
+	for x, row := range rows {
+		for _, cell := range strings.Fields(row) {
+			gem := models.GemType(cell)
+			if cell == "." {
+				gem = ""
+			} else if !isNotationGem(gem) {
+				return nil, fmt.Errorf("版图中的宝石 %q 无效", cell)
+			}
+			board[x] = append(board[x], gem)
+		}
+	}
+	return board, nil
+}
+
+// FormatMove 把一个动作写为棋谱中的一行
+func FormatMove(action GameAction) string {
+	switch action.Type {
+	case ActionTakeGems:
+		return "T " + formatCoords(action.TakeGems.Positions)
+	case ActionSpendPrivilege:
+		return "P " + formatCoords(action.SpendPrivilege.Positions)
+	case ActionRefillBoard:
+		return "F"
+	case ActionBuyCard:
+		cmd := action.BuyCard
+		line := "B " + cmd.CardID
+		if payment := formatGemCounts(cmd.Payment); payment != "" {
+			line += " pay " + payment
+		}
+		if cmd.AutoPay {
+			line += " auto"
+		}
+		if len(cmd.Choices) > 0 {
+			var choices []string
+			for decision, option := range cmd.Choices {
+				choices = append(choices, string(decision)+"="+formatOption(option))
+			}
+			sort.Strings(choices)
+			line += " with " + strings.Join(choices, " ")
+		}
+		return line
+	case ActionReserveCard:
+		cmd := action.ReserveCard
+		source := cmd.CardID
+		if source == "" {
+			source = "deck" + strconv.Itoa(int(cmd.DeckLevel))
+		}
+		return fmt.Sprintf("R %s gold %s", source, formatCoord(cmd.Gold))
+	case ActionDiscardGems:
+		return "D " + formatGemCounts(action.DiscardGems.Gems)
+	case ActionResolveDecision:
+		return "C " + formatOption(action.ResolveDecision.Choice)
+	}
+	return "; " + string(action.Type)
+}
+
+func formatCoord(c models.Coord) string {
+	return fmt.Sprintf("%d,%d", c.X, c.Y)
+}
+
+func formatCoords(coords []models.Coord) string {
+	parts := make([]string, len(coords))
+	for i, c := range coords {
+		parts[i] = formatCoord(c)
+	}
+	return strings.Join(parts, " ")
+}
+
+// 宝石数量按固定的宝石顺序写出，如 red2 gold1
+func formatGemCounts(gems map[models.GemType]int) string {
+	var parts []string
+	for _, supply := range gemSupply {
+		if count := gems[supply.Gem]; count > 0 {
+			parts = append(parts, fmt.Sprintf("%s%d", supply.Gem, count))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func formatOption(option models.DecisionOption) string {
+	switch {
+	case option.Position != nil:
+		return formatCoord(*option.Position)
+	case option.Gem != "":
+		return string(option.Gem)
+	}
+	return option.NobleID
+}
+
+// ParseNotation 解析文本棋谱
+func ParseNotation(text string) (*Notation, error) {
+	notation := &Notation{Tags: make(map[string]string)}
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			name, value, err := parseTag(line)
+			if err != nil {
+				return nil, fmt.Errorf("第 %d 行: %w", lineNumber, err)
+			}
+			notation.Tags[name] = value
+			continue
+		}
+		action, err := ParseMove(line)
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 行 %q: %w", lineNumber, line, err)
+		}
+		notation.Moves = append(notation.Moves, NotationMove{Line: lineNumber, Text: line, Action: action})
+	}
+	return notation, scanner.Err()
+}
+
+func parseTag(line string) (string, string, error) {
+	if !strings.HasSuffix(line, "]") {
+		return "", "", errors.New("标签缺少 ]")
+	}
+	name, quoted, ok := strings.Cut(strings.TrimSpace(line[1:len(line)-1]), " ")
+	if !ok {
+		return "", "", errors.New("标签缺少值")
+	}
+	value, err := strconv.Unquote(strings.TrimSpace(quoted))
+	if err != nil {
+		return "", "", fmt.Errorf("标签 %s 的值无效: %w", name, err)
+	}
+	return name, value, nil
+}
+
+// ParseMove 解析棋谱中的一行动作（不含执行者）
+func ParseMove(line string) (GameAction, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return GameAction{}, errors.New("空动作")
+	}
+	args := fields[1:]
+
+	switch fields[0] {
+	case "T", "P":
+		positions, err := parseCoords(args)
+		if err != nil {
+			return GameAction{}, err
+		}
+		if fields[0] == "T" {
+			return GameAction{Type: ActionTakeGems, TakeGems: &TakeGemsCmd{Positions: positions}}, nil
+		}
+		return GameAction{Type: ActionSpendPrivilege, SpendPrivilege: &SpendPrivilegeCmd{Positions: positions}}, nil
+	case "F":
+		return GameAction{Type: ActionRefillBoard}, nil
+	case "B":
+		return parseBuy(args)
+	case "R":
+		if len(args) != 3 || args[1] != "gold" {
+			return GameAction{}, errors.New("保留发展卡的格式为 R <卡牌ID|deckN> gold x,y")
+		}
+		gold, err := parseCoord(args[2])
+		if err != nil {
+			return GameAction{}, err
+		}
+		cmd := ReserveCardCmd{Gold: gold}
+		switch source := args[0]; {
+		case strings.HasPrefix(source, "deck"):
+			level, err := strconv.Atoi(strings.TrimPrefix(source, "deck"))
+			if err != nil || level < int(models.Level1) || level > int(models.Level3) {
+				return GameAction{}, fmt.Errorf("牌堆等级 %q 无效", source)
+			}
+			cmd.DeckLevel = models.CardLevel(level)
+		default:
+			cmd.CardID = source
+		}
+		return GameAction{Type: ActionReserveCard, ReserveCard: &cmd}, nil
+	case "D":
+		gems, err := parseGemCounts(args)
+		if err != nil {
+			return GameAction{}, err
+		}
+		if len(gems) == 0 {
+			return GameAction{}, errors.New("缺少要丢弃的宝石")
+		}
+		return GameAction{Type: ActionDiscardGems, DiscardGems: &DiscardGemsCmd{Gems: gems}}, nil
+	case "C":
+		if len(args) != 1 {
+			return GameAction{}, errors.New("决策的格式为 C <x,y|宝石|贵族ID>")
+		}
+		option, err := parseOption(args[0])
+		if err != nil {
+			return GameAction{}, err
+		}
+		return GameAction{Type: ActionResolveDecision, ResolveDecision: &ResolveDecisionCmd{Choice: option}}, nil
+	}
+	return GameAction{}, fmt.Errorf("未知的动作 %q", fields[0])
+}
+
+// B <卡牌ID> [pay <宝石><数量>...] [auto] [with <决策>=<选择>...]
+func parseBuy(args []string) (GameAction, error) {
+	if len(args) == 0 {
+		return GameAction{}, errors.New("缺少要购买的卡牌ID")
+	}
+	cmd := BuyCardCmd{CardID: args[0], Payment: map[models.GemType]int{}}
+	section := ""
+	for _, arg := range args[1:] {
+		switch arg {
+		case "pay", "with":
+			section = arg
+			continue
+		case "auto":
+			cmd.AutoPay = true
+			continue
+		}
+
+		switch section {
+		case "pay":
+			gems, err := parseGemCounts([]string{arg})
+			if err != nil {
+				return GameAction{}, err
+			}
+			for gem, count := range gems {
+				cmd.Payment[gem] += count
+			}
+		case "with":
+			decision, value, ok := strings.Cut(arg, "=")
+			if !ok {
+				return GameAction{}, fmt.Errorf("决策 %q 的格式应为 类型=选择", arg)
+			}
+			option, err := parseOption(value)
+			if err != nil {
+				return GameAction{}, err
+			}
+			if cmd.Choices == nil {
+				cmd.Choices = make(map[models.DecisionType]models.DecisionOption)
+			}
+			cmd.Choices[models.DecisionType(decision)] = option
+		default:
+			return GameAction{}, fmt.Errorf("无法识别 %q", arg)
+		}
+	}
+	return GameAction{Type: ActionBuyCard, BuyCard: &cmd}, nil
+}
+
+func parseCoord(text string) (models.Coord, error) {
+	xs, ys, ok := strings.Cut(text, ",")
+	x, errX := strconv.Atoi(xs)
+	y, errY := strconv.Atoi(ys)
+	if !ok || errX != nil || errY != nil {
+		return models.Coord{}, fmt.Errorf("坐标 %q 的格式应为 x,y", text)
+	}
+	return models.Coord{X: x, Y: y}, nil
+}
+
+func parseCoords(args []string) ([]models.Coord, error) {
+	if len(args) == 0 {
+		return nil, errors.New("缺少版图坐标")
+	}
+	coords := make([]models.Coord, 0, len(args))
+	for _, arg := range args {
+		c, err := parseCoord(arg)
+		if err != nil {
+			return nil, err
+		}
+		coords = append(coords, c)
+	}
+	return coords, nil
+}
+
+// 解析 red2 gold1 形式的宝石数量
+func parseGemCounts(args []string) (map[models.GemType]int, error) {
+	gems := make(map[models.GemType]int)
+	for _, arg := range args {
+		split := strings.IndexAny(arg, "0123456789")
+		if split <= 0 {
+			return nil, fmt.Errorf("宝石数量 %q 的格式应为 <宝石><数量>，如 red2", arg)
+		}
+		gem := models.GemType(arg[:split])
+		count, err := strconv.Atoi(arg[split:])
+		if err != nil || count <= 0 || !isNotationGem(gem) {
+			return nil, fmt.Errorf("宝石数量 %q 无效", arg)
+		}
+		gems[gem] += count
+	}
+	return gems, nil
+}
+
+// 决策选择：含逗号为版图位置，宝石名为颜色，其余为贵族ID
+func parseOption(text string) (models.DecisionOption, error) {
+	if strings.Contains(text, ",") {
+		c, err := parseCoord(text)
+		if err != nil {
+			return models.DecisionOption{}, err
+		}
+		return models.DecisionOption{Position: &c}, nil
+	}
+	if isNotationGem(models.GemType(text)) {
+		return models.DecisionOption{Gem: models.GemType(text)}, nil
+	}
+	if text == "" {
+		return models.DecisionOption{}, errors.New("缺少决策选择")
+	}
+	return models.DecisionOption{NobleID: text}, nil
+}
+
+func isNotationGem(gem models.GemType) bool {
+	for _, supply := range gemSupply {
+		if supply.Gem == gem {
+			return true
+		}
+	}
+	return false
+}
+
+// Replay 按棋谱重建对局：由头部的规则、玩家与种子创建开局前的状态，开始游戏后核对初始布局，
+// 再逐行执行动作；返回最终状态，任何一行被拒绝时返回带行号的错误
+func (n *Notation) Replay() (models.GameState, error) {
+	if catalog := n.Tags["Catalog"]; catalog != "" && catalog != CurrentCatalog().Version() {
+		return models.GameState{}, fmt.Errorf("棋谱使用的卡牌目录 %s 与当前目录 %s 不同", catalog, CurrentCatalog().Version())
+	}
+	seedText, ok := n.Tags["Seed"]
+	if !ok {
+		return models.GameState{}, errors.New("棋谱不含随机种子（对局结束前导出），无法重放")
+	}
+	seed, err := strconv.ParseInt(seedText, 10, 64)
+	if err != nil {
+		return models.GameState{}, fmt.Errorf("随机种子 %q 无效", seedText)
+	}
+	if commitment, ok := n.Tags["SeedCommitment"]; ok && commitment != SeedCommitment(seed) {
+		return models.GameState{}, fmt.Errorf("随机种子与承诺 %s 不符", commitment)
+	}
+	rules, ok := RuleSetByName(n.Tags["Rules"])
+	if !ok {
+		return models.GameState{}, fmt.Errorf("未知的规则预设 %q", n.Tags["Rules"])
+	}
+
+	state := NewGameState(rules, seed)
+	for i := 1; ; i++ {
+		name, ok := n.Tags[fmt.Sprintf("Player%d", i)]
+		if !ok {
+			break
+		}
+		id := n.Tags[fmt.Sprintf("Player%dId", i)]
+		if id == "" {
+			id = fmt.Sprintf("player%d", i)
+		}
+		state.Players = append(state.Players, models.Player{
+			ID:               id,
+			Name:             name,
+			Gems:             make(map[models.GemType]int),
+			Bonus:            make(map[models.GemType]int),
+			ReservedCards:    []string{},
+			DevelopmentCards: []string{},
+			Nobles:           []string{},
+			IsHost:           i == 1,
+		})
+	}
+
+	state, _, err = Apply(state, GameAction{Type: ActionStartGame})
+	if err != nil {
+		return models.GameState{}, fmt.Errorf("开始游戏失败: %w", err)
+	}
+	if err := n.checkLayout(state); err != nil {
+		return models.GameState{}, fmt.Errorf("初始布局与棋谱不符: %w", err)
+	}
+
+	for _, move := range n.Moves {
+		action := move.Action
+		action.PlayerID = actingPlayer(&state)
+		next, _, err := Apply(state, action)
+		if err != nil {
+			return state, fmt.Errorf("第 %d 行 %q: %w", move.Line, move.Text, err)
+		}
+		state = next
+	}
+	return state, nil
+}
+
+// 核对头部写明的起始玩家、初始版图与翻开的发展卡（未写明的项不核对）
+func (n *Notation) checkLayout(state models.GameState) error {
+	expected := observe(&state)
+	if first, ok := n.Tags["First"]; ok {
+		index, err := strconv.Atoi(first)
+		if err != nil {
+			return fmt.Errorf("起始玩家 %q 无效", first)
+		}
+		expected.CurrentPlayerIndex = index - 1
+	}
+	if text, ok := n.Tags["Board"]; ok {
+		board, err := parseBoard(text)
+		if err != nil {
+			return err
+		}
+		expected.GemBoard = board
+	}
+	for level := models.Level1; level <= models.Level3; level++ {
+		if text, ok := n.Tags[fmt.Sprintf("Level%d", level)]; ok {
+			expected.FlippedCards[level] = strings.Fields(text)
+		}
+	}
+	return compareOutcome(observe(&state), expected)
+}
+
+// 当前应当行动的玩家：待决策的玩家、需要丢弃宝石的玩家，否则为当前回合的玩家
+func actingPlayer(state *models.GameState) string {
+	if len(state.PendingDecisions) > 0 {
+		return state.PendingDecisions[0].PlayerID
+	}
+	if state.NeedsGemDiscard && state.GemDiscardPlayerID != "" {
+		return state.GemDiscardPlayerID
+	}
+	if state.CurrentPlayerIndex < len(state.Players) {
+		return state.Players[state.CurrentPlayerIndex].ID
+	}
+	return ""
+}
@@ -0,0 +1,175 @@
+package game
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"splendor-duel-backend/internal/models"
+)
+
+// 对局结束后导出的棋谱经解析、重放得到与服务端完全相同的最终状态
+func TestNotationRoundTrip(t *testing.T) {
+	for seed := int64(1); seed <= 10; seed++ {
+		m, roomID := newTestManager(seed)
+		playTestGame(t, m, roomID, seed)
+		final := m.rooms[roomID].GameState
+
+		text, err := m.ExportNotation(roomID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		notation, err := ParseNotation(text)
+		if err != nil {
+			t.Fatalf("种子 %d: %v\n%s", seed, err, text)
+		}
+		record, _ := m.GameRecord(roomID)
+		if len(notation.Moves) != len(record.Steps)-1 {
+			t.Fatalf("种子 %d: 棋谱有 %d 个动作，记录有 %d 个", seed, len(notation.Moves), len(record.Steps)-1)
+		}
+		for _, move := range notation.Moves {
+			if formatted := FormatMove(move.Action); formatted != move.Text {
+				t.Fatalf("第 %d 行 %q 重新格式化为 %q", move.Line, move.Text, formatted)
+			}
+		}
+
+		replayed, err := notation.Replay()
+		if err != nil {
+			t.Fatalf("种子 %d: %v", seed, err)
+		}
+		if !reflect.DeepEqual(withoutTimes(replayed), withoutTimes(final)) {
+			t.Fatalf("种子 %d: 重放得到的最终状态与对局不同", seed)
+		}
+	}
+}
+
+func TestNotationReplayRejectsTampering(t *testing.T) {
+	m, roomID := newTestManager(4)
+	playTestGame(t, m, roomID, 4)
+	text, err := m.ExportNotation(roomID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	notation, _ := ParseNotation(text)
+
+	tests := []struct {
+		name   string
+		tamper func(tags map[string]string, moves []NotationMove) []NotationMove
+		want   string
+	}{
+		{"起始玩家", func(tags map[string]string, moves []NotationMove) []NotationMove {
+			if tags["First"] == "1" {
+				tags["First"] = "2"
+			} else {
+				tags["First"] = "1"
+			}
+			return moves
+		}, "初始布局"},
+		{"初始版图", func(tags map[string]string, moves []NotationMove) []NotationMove {
+			tags["Board"] = strings.Repeat("-", 5) + tags["Board"][5:]
+			return moves
+		}, "初始布局"},
+		{"种子与承诺不符", func(tags map[string]string, moves []NotationMove) []NotationMove {
+			tags["Seed"] = "5"
+			return moves
+		}, "承诺"},
+		{"没有种子", func(tags map[string]string, moves []NotationMove) []NotationMove {
+			delete(tags, "Seed")
+			return moves
+		}, "种子"},
+		{"卡牌目录", func(tags map[string]string, moves []NotationMove) []NotationMove {
+			tags["Catalog"] = "000000000000"
+			return moves
+		}, "卡牌目录"},
+		{"规则", func(tags map[string]string, moves []NotationMove) []NotationMove {
+			tags["Rules"] = "no-such-rules"
+			return moves
+		}, "规则"},
+		{"删除一个动作", func(_ map[string]string, moves []NotationMove) []NotationMove {
+			return append(moves[:1:1], moves[2:]...)
+		}, "第"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tags := make(map[string]string, len(notation.Tags))
+			for name, value := range notation.Tags {
+				tags[name] = value
+			}
+			moves := tt.tamper(tags, append([]NotationMove(nil), notation.Moves...))
+			_, err := (&Notation{Tags: tags, Moves: moves}).Replay()
+			if err == nil {
+				t.Fatal("被篡改的棋谱重放成功")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("错误信息应包含 %q，实际 %v", tt.want, err)
+			}
+		})
+	}
+}
+
+// 未结束的对局导出时只有承诺没有种子，无法重放
+func TestNotationOfUnfinishedGame(t *testing.T) {
+	m, roomID := newTestManager(6)
+	if _, err := m.ExportNotation(roomID); CodeOf(err, "") != ErrGameNotStarted {
+		t.Fatalf("开始前不能导出棋谱，实际 %v", err)
+	}
+	if _, err := m.ApplyAction(roomID, GameAction{Type: ActionStartGame}); err != nil {
+		t.Fatal(err)
+	}
+	room := m.rooms[roomID]
+	if _, err := m.ApplyAction(roomID, takeGemsAction(t, &room.GameState)); err != nil {
+		t.Fatal(err)
+	}
+
+	text, err := m.ExportNotation(roomID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(text, `[Result "*"]`) || strings.Contains(text, "[Seed ") {
+		t.Fatalf("未结束的棋谱应不含种子且结果为 *:\n%s", text)
+	}
+	notation, err := ParseNotation(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notation.Moves) != 1 {
+		t.Fatalf("应有 1 个动作，实际 %d", len(notation.Moves))
+	}
+	if _, err := notation.Replay(); err == nil {
+		t.Fatal("没有种子的棋谱重放成功")
+	}
+}
+
+func TestParseMove(t *testing.T) {
+	valid := []struct {
+		text string
+		want GameAction
+	}{
+		{"T 2,2 2,3 2,4", GameAction{Type: ActionTakeGems, TakeGems: &TakeGemsCmd{Positions: []models.Coord{{X: 2, Y: 2}, {X: 2, Y: 3}, {X: 2, Y: 4}}}}},
+		{"P 1,1", GameAction{Type: ActionSpendPrivilege, SpendPrivilege: &SpendPrivilegeCmd{Positions: []models.Coord{{X: 1, Y: 1}}}}},
+		{"F", GameAction{Type: ActionRefillBoard}},
+		{"R deck2 gold 0,4", GameAction{Type: ActionReserveCard, ReserveCard: &ReserveCardCmd{DeckLevel: models.Level2, Gold: models.Coord{X: 0, Y: 4}}}},
+		{"D blue2 red1", GameAction{Type: ActionDiscardGems, DiscardGems: &DiscardGemsCmd{Gems: map[models.GemType]int{models.GemRed: 1, models.GemBlue: 2}}}},
+		{"C red", GameAction{Type: ActionResolveDecision, ResolveDecision: &ResolveDecisionCmd{Choice: models.DecisionOption{Gem: models.GemRed}}}},
+	}
+	for _, tt := range valid {
+		t.Run(tt.text, func(t *testing.T) {
+			got, err := ParseMove(tt.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("解析为 %+v，期望 %+v", got, tt.want)
+			}
+			if formatted := FormatMove(got); formatted != tt.text {
+				t.Fatalf("重新格式化为 %q", formatted)
+			}
+		})
+	}
+
+	for _, text := range []string{"X 1", "T", "T 1;2", "B", "B a1 pay purple2", "R a1 1,2", "R deck9 gold 1,1", "R deck gold 1,1", "D", "C", "E", "B a1 with steal"} {
+		if _, err := ParseMove(text); err == nil {
+			t.Errorf("%q 应解析失败", text)
+		}
+	}
+}
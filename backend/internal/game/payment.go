@@ -0,0 +1,154 @@
+package game
+
+import (
+	"sort"
+	"splendor-duel-backend/internal/models"
+)
+
+// SuggestPaymentPlans 列出玩家购买卡牌的全部有效支付方案
+// 按使用黄金从少到多排序，黄金相同时优先保留玩家手中较少的颜色；买不起时返回空列表
+func (gl *GameLogic) SuggestPaymentPlans(playerID string, cardID string) ([]map[models.GemType]int, error) {
+	player := gl.getPlayer(playerID)
+	if player == nil {
+		return nil, NewActionError(ErrPlayerNotFound, "玩家不存在")
+	}
+	if !gl.isCardBuyable(player, cardID) {
+		return nil, NewActionError(ErrCardNotFound, "卡牌不存在或无法购买")
+	}
+
+	card := gl.gameState.CardMap[cardID]
+	required := gl.calculateRequiredGems(&DevelopmentCardData{Cost: card.Cost}, player)
+	return rankPaymentPlans(player, paymentPlans(player, required)), nil
+}
+
+// 卡牌是否为场上翻开的卡牌或该玩家的保留卡，且玩家满足购买条件
+func (gl *GameLogic) isCardBuyable(player *models.Player, cardID string) bool {
+	card, ok := gl.gameState.CardMap[cardID]
+	if !ok || !canAssignWildcard(player, card) {
+		return false
+	}
+	for _, reserved := range player.ReservedCards {
+		if reserved == cardID {
+			return true
+		}
+	}
+	for level := models.Level1; level <= models.Level3; level++ {
+		for _, flipped := range gl.gameState.FlippedCards[level] {
+			if flipped == cardID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// 百搭卡只能计入已拥有的发展卡颜色，玩家还没有任何有颜色的发展卡时不能购买
+func canAssignWildcard(player *models.Player, card models.DevelopmentCard) bool {
+	hasWildcard := false
+	for _, effect := range card.Effects {
+		if effect == models.Wildcard {
+			hasWildcard = true
+		}
+	}
+	if !hasWildcard {
+		return true
+	}
+	for _, color := range wildcardColors {
+		if player.Bonus[color] > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// 对支付方案排序：先比较使用的黄金数量，再比较支付后各颜色的剩余数量
+// （从少到多逐项比较，剩余越多越好），即尽量不动用稀缺的颜色
+func rankPaymentPlans(player *models.Player, plans []map[models.GemType]int) []map[models.GemType]int {
+	remaining := make([][]int, len(plans))
+	for i, plan := range plans {
+		remaining[i] = remainingAfterPayment(player, plan)
+	}
+
+	order := make([]int, len(plans))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		pa, pb := plans[order[a]], plans[order[b]]
+		if pa[models.GemGold] != pb[models.GemGold] {
+			return pa[models.GemGold] < pb[models.GemGold]
+		}
+		ra, rb := remaining[order[a]], remaining[order[b]]
+		for k := range ra {
+			if ra[k] != rb[k] {
+				return ra[k] > rb[k]
+			}
+		}
+		return false
+	})
+
+	ranked := make([]map[models.GemType]int, len(plans))
+	for i, idx := range order {
+		ranked[i] = plans[idx]
+	}
+	return ranked
+}
+
+// 支付后玩家持有的各非黄金宝石数量，从少到多排列
+func remainingAfterPayment(player *models.Player, plan map[models.GemType]int) []int {
+	var counts []int
+	for gemType, own := range player.Gems {
+		if gemType == models.GemGold || own <= 0 {
+			continue
+		}
+		counts = append(counts, own-plan[gemType])
+	}
+	sort.Ints(counts)
+	return counts
+}
+
+// 列出支付应付费用的全部不同方案：每种颜色可用黄金替代任意数量
+func paymentPlans(player *models.Player, required map[models.GemType]int) []map[models.GemType]int {
+	var colors []models.GemType
+	for gem, count := range required {
+		if count > 0 {
+			colors = append(colors, gem)
+		}
+	}
+	sort.Slice(colors, func(i, j int) bool { return colors[i] < colors[j] })
+
+	var plans []map[models.GemType]int
+	paid := make(map[models.GemType]int)
+	var choose func(i, goldLeft int)
+	choose = func(i, goldLeft int) {
+		if i == len(colors) {
+			plan := make(map[models.GemType]int)
+			for gem, count := range paid {
+				if count > 0 {
+					plan[gem] = count
+				}
+			}
+			if gold := player.Gems[models.GemGold] - goldLeft; gold > 0 {
+				plan[models.GemGold] = gold
+			}
+			plans = append(plans, plan)
+			return
+		}
+		gem := colors[i]
+		need := required[gem]
+		own := player.Gems[gem]
+		if own > need {
+			own = need
+		}
+		for count := own; count >= 0; count-- {
+			if need-count > goldLeft {
+				break
+			}
+			paid[gem] = count
+			choose(i+1, goldLeft-(need-count))
+		}
+		delete(paid, gem)
+	}
+	choose(0, player.Gems[models.GemGold])
+	return plans
+}
@@ -0,0 +1,64 @@
+package game
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+
+	"splendor-duel-backend/internal/models"
+)
+
+// RandomSource 随机数来源，Intn 返回 [0, n) 内的整数
+// 机器人与测试可以注入自己的来源以进行确定性模拟
+type RandomSource interface {
+	Intn(n int) int
+}
+
+// SetRandomSource 指定该实例执行动作时使用的随机数来源
+func (gl *GameLogic) SetRandomSource(random RandomSource) {
+	gl.random = random
+}
+
+func (gl *GameLogic) randomSource() RandomSource {
+	if gl.random != nil {
+		return gl.random
+	}
+	return seededSource{state: gl.gameState}
+}
+
+// 由游戏种子确定的随机数来源：第 k 次抽取的结果只取决于种子与 k
+// 抽取次数记录在游戏状态中，因此复制状态后可以从同一位置继续
+type seededSource struct {
+	state *models.GameState
+}
+
+func (s seededSource) Intn(n int) int {
+	s.state.RandomDraws++
+	return int(splitmix64(uint64(s.state.Seed)+s.state.RandomDraws*0x9E3779B97F4A7C15) % uint64(n))
+}
+
+// splitmix64 的混合函数
+func splitmix64(z uint64) uint64 {
+	z = (z ^ (z >> 30)) * 0xBF58476D1CE4E5B9
+	z = (z ^ (z >> 27)) * 0x94D049BB133111EB
+	return z ^ (z >> 31)
+}
+
+// NewSeed 由 crypto/rand 生成新的随机种子；读取失败时返回错误，不能退回到固定的种子
+func NewSeed() (int64, error) {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return 0, fmt.Errorf("生成随机种子失败: %w", err)
+	}
+	return int64(binary.LittleEndian.Uint64(b[:])), nil
+}
+
+// SeedCommitment 种子的承诺值：十进制种子字符串的 SHA-256（十六进制）
+// 也可以手动校验，例如 echo -n <seed> | sha256sum
+func SeedCommitment(seed int64) string {
+	sum := sha256.Sum256([]byte(strconv.FormatInt(seed, 10)))
+	return hex.EncodeToString(sum[:])
+}
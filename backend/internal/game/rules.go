@@ -0,0 +1,96 @@
+package game
+
+import (
+	"sort"
+
+	"splendor-duel-backend/internal/models"
+)
+
+// 官方规则的预设名
+const OfficialRuleSet = "official"
+
+// 规则预设：官方规则与常用的房规
+var ruleSetPresets = map[string]models.RuleSet{
+	OfficialRuleSet: {
+		Name:               OfficialRuleSet,
+		VictoryPoints:      20,
+		VictoryCrowns:      10,
+		VictoryColorPoints: 10,
+		MaxTokens:          10,
+		MaxReserved:        3,
+		MaxPrivileges:      3,
+		FaceUpCards:        map[models.CardLevel]int{models.Level1: 5, models.Level2: 4, models.Level3: 3},
+		StalemateRule:      models.StalematePass,
+	},
+	// 快速局：胜利阈值降低，出现僵局时直接按分数结束
+	"short": {
+		Name:               "short",
+		VictoryPoints:      15,
+		VictoryCrowns:      8,
+		VictoryColorPoints: 8,
+		MaxTokens:          10,
+		MaxReserved:        3,
+		MaxPrivileges:      3,
+		FaceUpCards:        map[models.CardLevel]int{models.Level1: 5, models.Level2: 4, models.Level3: 3},
+		StalemateRule:      models.StalemateScore,
+	},
+	// 宽松局：更高的token与保留上限，场上翻开更多卡牌
+	"relaxed": {
+		Name:               "relaxed",
+		VictoryPoints:      20,
+		VictoryCrowns:      10,
+		VictoryColorPoints: 10,
+		MaxTokens:          12,
+		MaxReserved:        4,
+		MaxPrivileges:      3,
+		FaceUpCards:        map[models.CardLevel]int{models.Level1: 6, models.Level2: 5, models.Level3: 4},
+		StalemateRule:      models.StalematePass,
+	},
+}
+
+// DefaultRuleSet 返回官方规则
+func DefaultRuleSet() models.RuleSet {
+	rules, _ := RuleSetByName(OfficialRuleSet)
+	return rules
+}
+
+// RuleSetByName 按预设名返回规则，名称为空时返回官方规则
+func RuleSetByName(name string) (models.RuleSet, bool) {
+	if name == "" {
+		name = OfficialRuleSet
+	}
+	preset, ok := ruleSetPresets[name]
+	if !ok {
+		return models.RuleSet{}, false
+	}
+	// 复制翻开数量映射，避免各房间共享同一份预设
+	rules := preset
+	rules.FaceUpCards = make(map[models.CardLevel]int, len(preset.FaceUpCards))
+	for level, count := range preset.FaceUpCards {
+		rules.FaceUpCards[level] = count
+	}
+	return rules, true
+}
+
+// RuleSetNames 按名称排序列出全部规则预设
+func RuleSetNames() []string {
+	names := make([]string, 0, len(ruleSetPresets))
+	for name := range ruleSetPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// 当前对局的规则；旧状态中没有规则时按官方规则处理
+func (gl *GameLogic) rules() models.RuleSet {
+	if gl.gameState.Rules.VictoryPoints == 0 {
+		return DefaultRuleSet()
+	}
+	return gl.gameState.Rules
+}
+
+// 指定等级翻开的发展卡数量
+func (gl *GameLogic) faceUpCount(level models.CardLevel) int {
+	return gl.rules().FaceUpCards[level]
+}
@@ -0,0 +1,110 @@
+package game
+
+import (
+	"fmt"
+
+	"splendor-duel-backend/internal/models"
+)
+
+// 在回合开始和可选动作之后检查当前玩家是否有必选动作：
+// 没有时若袋子不为空则强制补充版图（对手获得特权），补充后仍没有则按规则处理僵局
+func (gl *GameLogic) ensureMandatoryAction() {
+	for gl.gameState.Status == models.GameStatusPlaying {
+		player := &gl.gameState.Players[gl.gameState.CurrentPlayerIndex]
+		if gl.hasMandatoryAction(player) {
+			gl.gameState.ConsecutivePasses = 0
+			return
+		}
+
+		if len(gl.gameState.GemBag) > 0 && !gl.gameState.RefilledThisTurn {
+			placed := gl.fillBoardFromBag(player.ID)
+			gl.emit(BoardRefilled{PlayerID: player.ID, Placed: placed, Forced: true})
+			continue
+		}
+
+		gl.resolveStalemate(player)
+	}
+}
+
+// 玩家是否至少有一个必选动作（拿取宝石、保留发展卡或购买发展卡）
+func (gl *GameLogic) hasMandatoryAction(player *models.Player) bool {
+	if len(gl.takeableGemLines()) > 0 {
+		return true
+	}
+
+	if len(player.ReservedCards) < gl.rules().MaxReserved &&
+		len(gl.boardPositions(func(gem models.GemType) bool { return gem == models.GemGold })) > 0 {
+		for level := models.Level1; level <= models.Level3; level++ {
+			if len(gl.gameState.FlippedCards[level]) > 0 || gl.gameState.UnflippedCards[level] > 0 {
+				return true
+			}
+		}
+	}
+
+	candidates := append([]string(nil), player.ReservedCards...)
+	for level := models.Level1; level <= models.Level3; level++ {
+		candidates = append(candidates, gl.gameState.FlippedCards[level]...)
+	}
+	for _, cardID := range candidates {
+		if !gl.isCardBuyable(player, cardID) {
+			continue
+		}
+		card := gl.gameState.CardMap[cardID]
+		required := gl.calculateRequiredGems(&DevelopmentCardData{Cost: card.Cost}, player)
+		if len(paymentPlans(player, required)) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// 处理僵局：跳过该玩家的回合；所有玩家都连续无法行动或规则要求时按分数结束游戏
+func (gl *GameLogic) resolveStalemate(player *models.Player) {
+	if gl.rules().StalemateRule == models.StalemateScore {
+		gl.finishByScore("僵局：当前玩家无法执行任何必选动作")
+		return
+	}
+
+	gl.gameState.ConsecutivePasses++
+	// 无法行动时额外回合也无法使用
+	delete(gl.gameState.ExtraTurns, player.ID)
+	gl.emit(TurnPassed{PlayerID: player.ID})
+
+	if gl.gameState.ConsecutivePasses >= len(gl.gameState.Players) {
+		gl.finishByScore("僵局：所有玩家都无法执行任何必选动作")
+		return
+	}
+
+	gl.gameState.RefilledThisTurn = false
+	gl.nextTurn()
+}
+
+// 按分数结束游戏：分数高者获胜，同分比较皇冠，仍相同为平局
+func (gl *GameLogic) finishByScore(reason string) {
+	gl.gameState.Status = models.GameStatusFinished
+	gl.gameState.TurnPhase = models.PhaseEnd
+
+	var winner *models.Player
+	tied := false
+	for i := range gl.gameState.Players {
+		p := &gl.gameState.Players[i]
+		switch {
+		case winner == nil || p.Points > winner.Points || (p.Points == winner.Points && p.Crowns > winner.Crowns):
+			winner = p
+			tied = false
+		case p.Points == winner.Points && p.Crowns == winner.Crowns:
+			tied = true
+		}
+	}
+
+	if winner == nil || tied {
+		gl.gameState.Winner = ""
+		gl.gameState.VictoryReasons = []string{reason, "分数与皇冠均相同，平局"}
+		gl.emit(GameDrawn{Reasons: gl.gameState.VictoryReasons})
+		return
+	}
+
+	gl.gameState.Winner = winner.ID
+	gl.gameState.VictoryReasons = []string{reason, fmt.Sprintf("按分数判定获胜（%d 分，%d 个皇冠）", winner.Points, winner.Crowns)}
+	gl.emit(GameWon{PlayerID: winner.ID, Reasons: gl.gameState.VictoryReasons})
+}
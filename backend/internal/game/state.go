@@ -0,0 +1,130 @@
+package game
+
+import "splendor-duel-backend/internal/models"
+
+// cloneGameState 深拷贝游戏状态
+// 卡牌与贵族详情按值复制：动作只会整体替换映射中的卡牌，不会修改其内部的费用和效果
+func cloneGameState(gs *models.GameState) models.GameState {
+	clone := *gs
+
+	if gs.Players != nil {
+		clone.Players = make([]models.Player, len(gs.Players))
+		for i, p := range gs.Players {
+			p.Gems = cloneGemCounts(p.Gems)
+			p.Bonus = cloneGemCounts(p.Bonus)
+			p.ReservedCards = cloneStrings(p.ReservedCards)
+			p.BlindReservedCards = cloneStrings(p.BlindReservedCards)
+			p.DevelopmentCards = cloneStrings(p.DevelopmentCards)
+			p.Nobles = cloneStrings(p.Nobles)
+			clone.Players[i] = p
+		}
+	}
+
+	if gs.GemBoard != nil {
+		clone.GemBoard = make([][]models.GemType, len(gs.GemBoard))
+		for i, row := range gs.GemBoard {
+			clone.GemBoard[i] = cloneGems(row)
+		}
+	}
+	clone.GemBag = cloneGems(gs.GemBag)
+	clone.VictoryReasons = cloneStrings(gs.VictoryReasons)
+
+	if gs.UnflippedCards != nil {
+		clone.UnflippedCards = make(map[models.CardLevel]int, len(gs.UnflippedCards))
+		for level, count := range gs.UnflippedCards {
+			clone.UnflippedCards[level] = count
+		}
+	}
+	if gs.FlippedCards != nil {
+		clone.FlippedCards = make(map[models.CardLevel][]string, len(gs.FlippedCards))
+		for level, cards := range gs.FlippedCards {
+			clone.FlippedCards[level] = cloneStrings(cards)
+		}
+	}
+	clone.Level1Deck = cloneStrings(gs.Level1Deck)
+	clone.Level2Deck = cloneStrings(gs.Level2Deck)
+	clone.Level3Deck = cloneStrings(gs.Level3Deck)
+	clone.CardDetails = cloneCards(gs.CardDetails)
+	clone.CardMap = cloneCards(gs.CardMap)
+
+	clone.AvailableNobles = cloneStrings(gs.AvailableNobles)
+	if gs.NobleMap != nil {
+		clone.NobleMap = make(map[string]models.NobleCard, len(gs.NobleMap))
+		for id, noble := range gs.NobleMap {
+			clone.NobleMap[id] = noble
+		}
+	}
+	if gs.ExtraTurns != nil {
+		clone.ExtraTurns = make(map[string]int, len(gs.ExtraTurns))
+		for id, count := range gs.ExtraTurns {
+			clone.ExtraTurns[id] = count
+		}
+	}
+	if gs.Rules.FaceUpCards != nil {
+		clone.Rules.FaceUpCards = make(map[models.CardLevel]int, len(gs.Rules.FaceUpCards))
+		for level, count := range gs.Rules.FaceUpCards {
+			clone.Rules.FaceUpCards[level] = count
+		}
+	}
+	if gs.PendingDecisions != nil {
+		clone.PendingDecisions = make([]models.PendingDecision, len(gs.PendingDecisions))
+		for i, decision := range gs.PendingDecisions {
+			clone.PendingDecisions[i] = cloneDecision(decision)
+		}
+	}
+
+	return clone
+}
+
+// cloneDecision 深拷贝待处理决策，包括选项及其中的版图位置
+func cloneDecision(decision models.PendingDecision) models.PendingDecision {
+	if decision.Options == nil {
+		return decision
+	}
+	options := make([]models.DecisionOption, len(decision.Options))
+	for i, option := range decision.Options {
+		if option.Position != nil {
+			position := *option.Position
+			option.Position = &position
+		}
+		options[i] = option
+	}
+	decision.Options = options
+	return decision
+}
+
+func cloneGemCounts(counts map[models.GemType]int) map[models.GemType]int {
+	if counts == nil {
+		return nil
+	}
+	clone := make(map[models.GemType]int, len(counts))
+	for gem, count := range counts {
+		clone[gem] = count
+	}
+	return clone
+}
+
+func cloneGems(gems []models.GemType) []models.GemType {
+	if gems == nil {
+		return nil
+	}
+	return append([]models.GemType{}, gems...)
+}
+
+func cloneStrings(s []string) []string {
+	if s == nil {
+		return nil
+	}
+	return append([]string{}, s...)
+}
+
+func cloneCards(cards map[string]models.DevelopmentCard) map[string]models.DevelopmentCard {
+	if cards == nil {
+		return nil
+	}
+	clone := make(map[string]models.DevelopmentCard, len(cards))
+	for id, card := range cards {
+		clone[id] = card
+	}
+	return clone
+}
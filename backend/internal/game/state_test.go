@@ -0,0 +1,47 @@
+package game
+
+import (
+	"testing"
+
+	"splendor-duel-backend/internal/models"
+)
+
+// 修改副本的任何部分都不应影响原状态（包括待处理决策的选项与其中的版图位置）
+func TestCloneGameStateIsDeep(t *testing.T) {
+	state, _, err := Apply(newTestState(3), GameAction{Type: ActionStartGame})
+	if err != nil {
+		t.Fatal(err)
+	}
+	state.PendingDecisions = []models.PendingDecision{{
+		Type:     models.DecisionExtraToken,
+		PlayerID: state.Players[0].ID,
+		Options: []models.DecisionOption{
+			{Position: &models.Coord{X: 1, Y: 2}, Gem: models.GemRed},
+			{Gem: models.GemBlue},
+		},
+	}}
+	state.ExtraTurns[state.Players[0].ID] = 1
+	before := marshalState(t, state)
+
+	clone := cloneGameState(&state)
+	clone.Players[0].Gems[models.GemRed] += 5
+	clone.Players[0].Bonus[models.GemBlue]++
+	clone.Players[0].ReservedCards = append(clone.Players[0].ReservedCards[:0], "changed")
+	clone.GemBoard[0][0] = models.GemGold
+	clone.GemBag = append(clone.GemBag[:0], models.GemPearl)
+	clone.FlippedCards[models.Level1][0] = "changed"
+	clone.UnflippedCards[models.Level2] = 0
+	clone.Level3Deck[0] = "changed"
+	clone.ExtraTurns[state.Players[0].ID] = 9
+	clone.Rules.FaceUpCards[models.Level1] = 0
+	clone.PendingDecisions[0].Options[0].Position.X = 4
+	clone.PendingDecisions[0].Options[1].Gem = models.GemWhite
+	clone.PendingDecisions[0].Options = append(clone.PendingDecisions[0].Options, models.DecisionOption{})
+
+	if after := marshalState(t, state); after != before {
+		t.Fatal("修改副本影响了原状态")
+	}
+	if state.PendingDecisions[0].Options[0].Position == clone.PendingDecisions[0].Options[0].Position {
+		t.Fatal("决策选项的版图位置与副本共享")
+	}
+}
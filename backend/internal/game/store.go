@@ -0,0 +1,234 @@
+package game
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"splendor-duel-backend/internal/models"
+)
+
+// Store 房间数据的持久化接口：房间与游戏状态、操作记录和聊天
+// Manager 启动时从中加载房间，创建/加入房间以及每次提交动作后写入
+type Store interface {
+	// LoadRooms 返回保存的全部房间
+	LoadRooms() ([]StoredRoom, error)
+	// SaveRoom 保存房间（整体覆盖同ID的房间）
+	SaveRoom(room StoredRoom) error
+	// DeleteRoom 删除房间及其操作记录和聊天
+	DeleteRoom(roomID string) error
+
+	// LoadHistory 返回房间的操作记录（按时间顺序）
+	LoadHistory(roomID string) ([]models.GameAction, error)
+	// AppendHistory 追加一条操作记录
+	AppendHistory(roomID string, entry models.GameAction) error
+	// TruncateHistory 只保留前 length 条操作记录（悔棋时使用）
+	TruncateHistory(roomID string, length int) error
+
+	// LoadChat 返回房间的聊天消息（按时间顺序）
+	LoadChat(roomID string) ([]models.ChatMessage, error)
+	// AppendChat 追加一条聊天消息
+	AppendChat(roomID string, message models.ChatMessage) error
+}
+
+// StoredRoom 持久化的房间：房间与游戏状态，以及开局后的公平性记录
+type StoredRoom struct {
+	Room   models.Room `json:"room"`
+	Record *GameRecord `json:"record,omitempty"`
+}
+
+// MemoryStore 只保存在内存中的 Store，服务重启后数据丢失（默认使用，也用于测试）
+type MemoryStore struct {
+	mutex   sync.RWMutex
+	rooms   map[string]StoredRoom
+	history map[string][]models.GameAction
+	chat    map[string][]models.ChatMessage
+}
+
+// NewMemoryStore 创建内存存储
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		rooms:   make(map[string]StoredRoom),
+		history: make(map[string][]models.GameAction),
+		chat:    make(map[string][]models.ChatMessage),
+	}
+}
+
+func (s *MemoryStore) LoadRooms() ([]StoredRoom, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	rooms := make([]StoredRoom, 0, len(s.rooms))
+	for _, room := range s.rooms {
+		rooms = append(rooms, cloneStoredRoom(room))
+	}
+	return rooms, nil
+}
+
+func (s *MemoryStore) SaveRoom(room StoredRoom) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.rooms[room.Room.ID] = cloneStoredRoom(room)
+	return nil
+}
+
+func (s *MemoryStore) DeleteRoom(roomID string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.rooms, roomID)
+	delete(s.history, roomID)
+	delete(s.chat, roomID)
+	return nil
+}
+
+func (s *MemoryStore) LoadHistory(roomID string) ([]models.GameAction, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return append([]models.GameAction(nil), s.history[roomID]...), nil
+}
+
+func (s *MemoryStore) AppendHistory(roomID string, entry models.GameAction) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.history[roomID] = append(s.history[roomID], entry)
+	return nil
+}
+
+func (s *MemoryStore) TruncateHistory(roomID string, length int) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if history := s.history[roomID]; length < len(history) {
+		s.history[roomID] = history[:length:length]
+	}
+	return nil
+}
+
+func (s *MemoryStore) LoadChat(roomID string) ([]models.ChatMessage, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return append([]models.ChatMessage(nil), s.chat[roomID]...), nil
+}
+
+func (s *MemoryStore) AppendChat(roomID string, message models.ChatMessage) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.chat[roomID] = append(s.chat[roomID], message)
+	return nil
+}
+
+// 深拷贝房间，避免存储与调用方共享游戏状态
+func cloneStoredRoom(room StoredRoom) StoredRoom {
+	room.Room.GameState = cloneGameState(&room.Room.GameState)
+	if room.Record != nil {
+		record := *room.Record
+		record.Initial = cloneGameState(&room.Record.Initial)
+		record.Steps = append([]RecordStep(nil), room.Record.Steps...)
+		room.Record = &record
+	}
+	return room
+}
+
+// UseStore 使用指定的存储并从中加载未过期的房间；之后房间的每次变更都写入该存储
+// 无法加载的房间只记录日志并跳过，不影响服务启动
+func (m *Manager) UseStore(store Store) {
+	stored, err := store.LoadRooms()
+	if err != nil {
+		log.Printf("加载房间失败，跳过无法读取的房间: %v", err)
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.store = store
+	loaded := 0
+	for _, entry := range stored {
+		room := entry.Room
+		if time.Since(room.CreatedAt) > roomLifetime {
+			if err := store.DeleteRoom(room.ID); err != nil {
+				log.Printf("删除过期房间 %s 的存储失败: %v", room.ID, err)
+			}
+			continue
+		}
+		if violation := NewGameLogic(&room.GameState).CheckInvariants(); violation != nil {
+			log.Printf("房间 %s 的存储状态校验失败，跳过加载:\n%v", room.ID, violation)
+			continue
+		}
+		m.rooms[room.ID] = &room
+		if entry.Record != nil {
+			m.records[room.ID] = entry.Record
+		}
+		history, err := store.LoadHistory(room.ID)
+		if err != nil {
+			log.Printf("读取房间 %s 的操作记录失败: %v", room.ID, err)
+		}
+		m.historyLength[room.ID] = len(history)
+		loaded++
+	}
+	log.Printf("从存储加载了 %d 个房间", loaded)
+}
+
+// 把房间当前的状态与公平性记录写入存储；写入失败只记录日志，不影响已提交的动作（调用方持有 m.mutex）
+func (m *Manager) persist(roomID string) {
+	room, exists := m.rooms[roomID]
+	if !exists {
+		return
+	}
+	if err := m.store.SaveRoom(StoredRoom{Room: *room, Record: m.records[roomID]}); err != nil {
+		log.Printf("保存房间 %s 失败: %v", roomID, err)
+	}
+}
+
+// History 返回房间保存的操作记录
+func (m *Manager) History(roomID string) []models.GameAction {
+	history, err := m.currentStore().LoadHistory(roomID)
+	if err != nil {
+		log.Printf("读取房间 %s 的操作记录失败: %v", roomID, err)
+	}
+	return history
+}
+
+// AppendHistory 保存一条操作记录
+func (m *Manager) AppendHistory(roomID string, entry models.GameAction) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if err := m.store.AppendHistory(roomID, entry); err != nil {
+		log.Printf("保存房间 %s 的操作记录失败: %v", roomID, err)
+		return
+	}
+	m.historyLength[roomID]++
+}
+
+// 悔棋后只保留前 length 条操作记录（调用方持有 m.mutex）
+func (m *Manager) truncateHistory(roomID string, length int) {
+	if length >= m.historyLength[roomID] {
+		return
+	}
+	if err := m.store.TruncateHistory(roomID, length); err != nil {
+		log.Printf("截断房间 %s 的操作记录失败: %v", roomID, err)
+		return
+	}
+	m.historyLength[roomID] = length
+}
+
+// Chat 返回房间保存的聊天消息
+func (m *Manager) Chat(roomID string) []models.ChatMessage {
+	chat, err := m.currentStore().LoadChat(roomID)
+	if err != nil {
+		log.Printf("读取房间 %s 的聊天失败: %v", roomID, err)
+	}
+	return chat
+}
+
+// AppendChat 保存一条聊天消息
+func (m *Manager) AppendChat(roomID string, message models.ChatMessage) {
+	if err := m.currentStore().AppendChat(roomID, message); err != nil {
+		log.Printf("保存房间 %s 的聊天失败: %v", roomID, err)
+	}
+}
+
+func (m *Manager) currentStore() Store {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return m.store
+}
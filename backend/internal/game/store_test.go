@@ -0,0 +1,27 @@
+package game
+
+import (
+	"errors"
+	"testing"
+)
+
+// 读取房间总是失败的存储，模拟损坏或不可读的存储目录
+type failingStore struct {
+	*MemoryStore
+}
+
+func (s failingStore) LoadRooms() ([]StoredRoom, error) {
+	return nil, errors.New("存储不可读")
+}
+
+func TestUseStoreStartsEmptyWhenLoadFails(t *testing.T) {
+	m := NewManager()
+	store := failingStore{NewMemoryStore()}
+	m.UseStore(store)
+	if len(m.rooms) != 0 {
+		t.Fatalf("加载失败时应以空房间启动，实际 %d 个房间", len(m.rooms))
+	}
+	if m.store != store {
+		t.Fatal("加载失败后仍应使用该存储写入新的房间")
+	}
+}
@@ -0,0 +1,116 @@
+package game
+
+import "splendor-duel-backend/internal/models"
+
+// 每个房间最多保留的可撤销动作数
+const maxUndoSnapshots = 20
+
+// 可撤销的动作：执行前的完整状态、动作本身，以及执行前已保存的操作记录条数
+type undoSnapshot struct {
+	State         models.GameState
+	Action        GameAction
+	HistoryLength int
+}
+
+// 动作执行后记录快照；开局、结束以及揭示隐藏信息（翻开牌堆或随机补充版图）之后不能再撤销（调用方持有 m.mutex）
+func (m *Manager) recordUndo(roomID string, previous, next *models.GameState, action GameAction) {
+	delete(m.undoRequests, roomID)
+	if previous.Status != models.GameStatusPlaying || next.Status != models.GameStatusPlaying || revealsHiddenInformation(previous, next) {
+		delete(m.undo, roomID)
+		return
+	}
+
+	snapshots := append(m.undo[roomID], undoSnapshot{State: *previous, Action: action, HistoryLength: m.historyLength[roomID]})
+	if len(snapshots) > maxUndoSnapshots {
+		snapshots = snapshots[len(snapshots)-maxUndoSnapshots:]
+	}
+	m.undo[roomID] = snapshots
+}
+
+// 动作是否揭示了隐藏信息：从牌堆抽出的卡牌，或消耗了随机数（补充版图、强制补充与盲抽保留）
+// 撤销会恢复随机数进度，之后重新执行会得到同样的结果，而玩家已经看到了这个结果
+func revealsHiddenInformation(previous, next *models.GameState) bool {
+	return next.RandomDraws != previous.RandomDraws ||
+		len(next.Level1Deck) < len(previous.Level1Deck) ||
+		len(next.Level2Deck) < len(previous.Level2Deck) ||
+		len(next.Level3Deck) < len(previous.Level3Deck)
+}
+
+// RequestUndo 请求撤销自己的上一步动作，需要对手通过 RespondUndo 同意
+func (m *Manager) RequestUndo(roomID, playerID string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	room, exists := m.rooms[roomID]
+	if !exists {
+		return NewActionError(ErrRoomNotFound, "房间不存在")
+	}
+	if _, frozen := m.frozen[roomID]; frozen {
+		return NewActionError(ErrRoomFrozen, "房间状态异常，已冻结等待排查")
+	}
+	if room.GameState.Status != models.GameStatusPlaying {
+		return NewActionError(ErrUndoUnavailable, "游戏未在进行中，无法悔棋")
+	}
+	if _, pending := m.undoRequests[roomID]; pending {
+		return NewActionError(ErrUndoUnavailable, "已有等待回应的悔棋请求")
+	}
+
+	snapshots := m.undo[roomID]
+	if len(snapshots) == 0 {
+		return NewActionError(ErrUndoUnavailable, "没有可以撤销的动作（翻开牌堆或补充版图后不能悔棋）")
+	}
+	if snapshots[len(snapshots)-1].Action.PlayerID != playerID {
+		return NewActionError(ErrUndoUnavailable, "只能撤销自己的上一步动作")
+	}
+
+	m.undoRequests[roomID] = playerID
+	return nil
+}
+
+// RespondUndo 对手回应悔棋请求；同意时恢复到请求方上一步动作之前的状态并发布 ActionUndone 事件
+// 返回是否执行了撤销
+func (m *Manager) RespondUndo(roomID, playerID string, accept bool) (bool, error) {
+	m.mutex.Lock()
+	room, exists := m.rooms[roomID]
+	if !exists {
+		m.mutex.Unlock()
+		return false, NewActionError(ErrRoomNotFound, "房间不存在")
+	}
+	if _, frozen := m.frozen[roomID]; frozen {
+		m.mutex.Unlock()
+		return false, NewActionError(ErrRoomFrozen, "房间状态异常，已冻结等待排查")
+	}
+	requester, pending := m.undoRequests[roomID]
+	if !pending {
+		m.mutex.Unlock()
+		return false, NewActionError(ErrUndoUnavailable, "没有等待回应的悔棋请求")
+	}
+	if requester == playerID {
+		m.mutex.Unlock()
+		return false, NewActionError(ErrUndoUnavailable, "不能回应自己的悔棋请求")
+	}
+	if NewGameLogic(&room.GameState).getPlayer(playerID) == nil {
+		m.mutex.Unlock()
+		return false, NewActionError(ErrPlayerNotFound, "玩家不存在")
+	}
+
+	delete(m.undoRequests, roomID)
+	if !accept {
+		m.mutex.Unlock()
+		return false, nil
+	}
+
+	snapshots := m.undo[roomID]
+	last := snapshots[len(snapshots)-1]
+	m.undo[roomID] = snapshots[:len(snapshots)-1]
+	room.GameState = last.State
+	if record := m.records[roomID]; record != nil && len(record.Steps) > 0 {
+		record.Steps = record.Steps[:len(record.Steps)-1]
+	}
+	m.truncateHistory(roomID, last.HistoryLength)
+	m.persist(roomID)
+	m.mutex.Unlock()
+
+	m.publish(roomID, []Event{ActionUndone{PlayerID: requester, ApprovedBy: playerID, Action: last.Action}})
+	return true, nil
+}
@@ -0,0 +1,139 @@
+package game
+
+import (
+	"math/rand"
+	"testing"
+
+	"splendor-duel-backend/internal/models"
+)
+
+func applyWithHistory(t *testing.T, m *Manager, roomID string, action GameAction, entries int) {
+	t.Helper()
+	if _, err := m.ApplyAction(roomID, action); err != nil {
+		t.Fatalf("%s 被拒绝: %v", FormatMove(action), err)
+	}
+	for i := 0; i < entries; i++ {
+		m.AppendHistory(roomID, models.GameAction{PlayerID: action.PlayerID, Description: FormatMove(action)})
+	}
+}
+
+func TestUndoRestoresStateAndTruncatesHistory(t *testing.T) {
+	m, roomID := newTestManager(7)
+	applyWithHistory(t, m, roomID, GameAction{Type: ActionStartGame}, 2)
+	room := m.rooms[roomID]
+
+	first := takeGemsAction(t, &room.GameState)
+	applyWithHistory(t, m, roomID, first, 1)
+
+	before := marshalState(t, room.GameState)
+	second := takeGemsAction(t, &room.GameState)
+	applyWithHistory(t, m, roomID, second, 2)
+	if got := len(m.History(roomID)); got != 5 {
+		t.Fatalf("悔棋前应有 5 条操作记录，实际 %d", got)
+	}
+
+	if err := m.RequestUndo(roomID, first.PlayerID); err == nil {
+		t.Fatal("不能撤销对手的动作")
+	}
+	if err := m.RequestUndo(roomID, second.PlayerID); err != nil {
+		t.Fatalf("请求悔棋失败: %v", err)
+	}
+	if _, err := m.RespondUndo(roomID, second.PlayerID, true); err == nil {
+		t.Fatal("不能回应自己的悔棋请求")
+	}
+	undone, err := m.RespondUndo(roomID, first.PlayerID, true)
+	if err != nil || !undone {
+		t.Fatalf("同意悔棋失败: undone=%v err=%v", undone, err)
+	}
+
+	if after := marshalState(t, room.GameState); after != before {
+		t.Fatal("悔棋后的状态与动作执行前不同")
+	}
+	if got := len(m.History(roomID)); got != 3 {
+		t.Fatalf("悔棋后应保留动作之前的 3 条操作记录，实际 %d", got)
+	}
+	record, err := m.GameRecord(roomID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(record.Steps) != 2 {
+		t.Fatalf("公平性记录应只剩开始游戏与第一个动作，实际 %d 步", len(record.Steps))
+	}
+}
+
+func TestUndoUnavailableAfterRevealingDeck(t *testing.T) {
+	m, roomID := newTestManager(11)
+	applyWithHistory(t, m, roomID, GameAction{Type: ActionStartGame}, 0)
+	room := m.rooms[roomID]
+
+	playerID := room.GameState.Players[room.GameState.CurrentPlayerIndex].ID
+	var blind *GameAction
+	for _, action := range NewGameLogic(&room.GameState).LegalActions(playerID) {
+		if action.Type == ActionReserveCard && action.ReserveCard.DeckLevel != 0 {
+			blind = &action
+			break
+		}
+	}
+	if blind == nil {
+		t.Skip("开局版图上没有黄金，无法盲抽保留")
+	}
+	applyWithHistory(t, m, roomID, *blind, 1)
+
+	err := m.RequestUndo(roomID, playerID)
+	if CodeOf(err, "") != ErrUndoUnavailable {
+		t.Fatalf("盲抽保留后应不能悔棋，实际 %v", err)
+	}
+}
+
+func TestRespondUndoRejectedWhenFrozen(t *testing.T) {
+	m, roomID := newTestManager(3)
+	applyWithHistory(t, m, roomID, GameAction{Type: ActionStartGame}, 0)
+	room := m.rooms[roomID]
+
+	action := takeGemsAction(t, &room.GameState)
+	applyWithHistory(t, m, roomID, action, 1)
+	if err := m.RequestUndo(roomID, action.PlayerID); err != nil {
+		t.Fatalf("请求悔棋失败: %v", err)
+	}
+
+	m.frozen[roomID] = ""
+	state := marshalState(t, room.GameState)
+	opponent := room.GameState.Players[room.GameState.CurrentPlayerIndex].ID
+	if _, err := m.RespondUndo(roomID, opponent, true); CodeOf(err, "") != ErrRoomFrozen {
+		t.Fatalf("冻结的房间应拒绝悔棋，实际 %v", err)
+	}
+	if marshalState(t, room.GameState) != state || len(m.History(roomID)) != 1 {
+		t.Fatal("冻结的房间不应被悔棋修改")
+	}
+}
+
+// 补充版图会用随机数决定宝石位置，撤销后玩家仍会记住这个布局，因此不能悔棋
+func TestUndoUnavailableAfterRefill(t *testing.T) {
+	m, roomID := newTestManager(5)
+	applyWithHistory(t, m, roomID, GameAction{Type: ActionStartGame}, 0)
+	room := m.rooms[roomID]
+	random := rand.New(rand.NewSource(5))
+
+	for step := 0; step < 200 && room.GameState.Status == models.GameStatusPlaying; step++ {
+		action := nextTestAction(t, &room.GameState, random)
+		// 袋中至少有两个宝石时补充才需要洗袋
+		if len(room.GameState.PendingDecisions) == 0 && !room.GameState.NeedsGemDiscard && len(room.GameState.GemBag) >= 2 {
+			for _, legal := range NewGameLogic(&room.GameState).LegalActions(action.PlayerID) {
+				if legal.Type == ActionRefillBoard {
+					action = legal
+					break
+				}
+			}
+		}
+		applyWithHistory(t, m, roomID, action, 1)
+		if action.Type != ActionRefillBoard {
+			continue
+		}
+
+		if err := m.RequestUndo(roomID, action.PlayerID); CodeOf(err, "") != ErrUndoUnavailable {
+			t.Fatalf("补充版图后应不能悔棋，实际 %v", err)
+		}
+		return
+	}
+	t.Skip("测试对局中没有出现补充版图的机会")
+}
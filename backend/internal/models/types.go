@@ -38,6 +38,17 @@ const (
 	Steal          CardEffect = "steal"            // 窃取
 )
 
+// 回合阶段：可选动作 → 必选动作 → 效果结算 → 丢弃宝石 → 回合结束
+type TurnPhase string
+
+const (
+	PhaseOptional  TurnPhase = "optional"  // 回合开始，可执行可选动作或必选动作
+	PhaseMandatory TurnPhase = "mandatory" // 已补充版图，只能执行必选动作
+	PhaseEffects   TurnPhase = "effects"   // 必选动作后等待效果结算（如选择贵族）
+	PhaseDiscard   TurnPhase = "discard"   // 等待丢弃超出上限的宝石
+	PhaseEnd       TurnPhase = "end"       // 游戏在本回合结束
+)
+
 // 游戏状态常量
 const (
 	GameStatusWaiting  = "waiting"
@@ -57,6 +68,7 @@ type DevelopmentCard struct {
 	Cost        map[GemType]int   `json:"cost"`
 	Effects     []CardEffect      `json:"effects"`    // 一次性效果
 	IsSpecial   bool              `json:"isSpecial"`  // 是否为特殊卡
+	BonusCount  int               `json:"bonusCount"` // 提供的bonus数量
 	ImagePath   string            `json:"imagePath"`
 }
 
@@ -64,7 +76,8 @@ type DevelopmentCard struct {
 type NobleCard struct {
 	ID        string            `json:"id"`
 	Points    int               `json:"points"`
-	Requirement map[GemType]int `json:"requirement"`
+	Requirement map[GemType]int `json:"requirement,omitempty"`
+	Effects   []CardEffect      `json:"effects"`   // 获得时的一次性效果
 	ImagePath string            `json:"imagePath"`
 }
 
@@ -75,6 +88,7 @@ type Player struct {
 	Gems              map[GemType]int   `json:"gems"`                // 持有的7种宝石token数量
 	Bonus             map[GemType]int   `json:"bonus"`               // 持有的5种一般颜色bonus数量（来自发展卡）
 	ReservedCards     []string          `json:"reservedCards"`       // 保留的发展卡ID列表
+	BlindReservedCards []string         `json:"blindReservedCards,omitempty"` // 其中从牌堆盲抽保留的卡（对手不可见，打出后移除）
 	DevelopmentCards  []string          `json:"developmentCards"`    // 已获得的发展卡ID列表
 	PrivilegeTokens   int               `json:"privilegeTokens"`     // 特权指示物数量
 	Crowns            int               `json:"crowns"`              // 皇冠数量
@@ -89,6 +103,7 @@ type GameState struct {
 	Status                    string                        `json:"status"`                    // "waiting", "playing", "finished"
 	CurrentPlayerIndex        int                           `json:"currentPlayerIndex"`        // 当前玩家索引
 	TurnNumber                int                           `json:"turnNumber"`                // 回合数
+	TurnPhase                 TurnPhase                     `json:"turnPhase"`                 // 当前回合阶段
 	Players                   []Player                      `json:"players"`                   // 玩家列表
 	Winner                    string                        `json:"winner,omitempty"`          // 获胜者ID
 	VictoryReasons            []string                      `json:"victoryReasons,omitempty"`  // 获胜原因说明
@@ -109,6 +124,8 @@ type GameState struct {
 	
 	// 贵族卡
 	AvailableNobles           []string                      `json:"availableNobles"`           // 可获得的贵族ID列表
+	NobleMap                  map[string]NobleCard          `json:"nobleMap"`                  // 开局时从卡牌目录复制的贵族数据
+	CatalogVersion            string                        `json:"catalogVersion"`            // 开局时使用的卡牌目录版本
 	
 	// 额外回合
 	ExtraTurns                 map[string]int                `json:"extraTurns"`                // 每个玩家的额外回合数
@@ -119,16 +136,49 @@ type GameState struct {
 	// 可选动作顺序限制
 	RefilledThisTurn          bool                         `json:"refilledThisTurn"`         // 本回合是否已经执行过补充版图
 	
+	// 僵局：连续因无法行动而被跳过的回合数
+	ConsecutivePasses         int                           `json:"consecutivePasses"`        // 连续被跳过的回合数
+
 	// 宝石丢弃相关
 	NeedsGemDiscard           bool                          `json:"needsGemDiscard"`          // 是否需要丢弃宝石
 	GemDiscardTarget          int                           `json:"gemDiscardTarget"`         // 宝石丢弃目标数量
 	GemDiscardPlayerID        string                        `json:"gemDiscardPlayerID"`       // 需要丢弃宝石的玩家ID
-	
+
+	// 待处理的玩家决策（按顺序结算，全部结算后回合才能结束）
+	PendingDecisions          []PendingDecision             `json:"pendingDecisions"`         // 待决策队列，首项为当前决策
+
+	// 规则配置：创建房间时选定，所有规则检查从此读取
+	Rules                     RuleSet                       `json:"rules"`                    // 本局使用的规则
+
+	// 随机数：相同种子与抽取次数可复现完全相同的对局
+	Seed                      int64                         `json:"seed"`                     // 本局的随机种子
+	RandomDraws               uint64                        `json:"randomDraws"`              // 已抽取的随机数次数
+	SeedCommitment            string                        `json:"seedCommitment,omitempty"` // 开局时公布的种子承诺（SHA-256），游戏结束后公开种子以供校验
+
 	// 时间
 	CreatedAt                 time.Time                     `json:"createdAt"`
 	StartedAt                 time.Time                     `json:"startedAt,omitempty"`
 }
 
+// RuleSet 一局游戏的规则参数（胜利条件与各项上限）
+type RuleSet struct {
+	Name               string            `json:"name"`               // 规则名称（预设名）
+	VictoryPoints      int               `json:"victoryPoints"`      // 总分胜利阈值
+	VictoryCrowns      int               `json:"victoryCrowns"`      // 皇冠胜利阈值
+	VictoryColorPoints int               `json:"victoryColorPoints"` // 单色发展卡总分胜利阈值
+	MaxTokens          int               `json:"maxTokens"`          // 回合结束时玩家持有token上限
+	MaxReserved        int               `json:"maxReserved"`        // 保留区上限
+	MaxPrivileges      int               `json:"maxPrivileges"`      // 特权指示物总数（也是单个玩家的上限）
+	FaceUpCards        map[CardLevel]int `json:"faceUpCards"`        // 各等级翻开的发展卡数量
+	StalemateRule      string            `json:"stalemateRule"`      // 无任何必选动作时的处理方式
+}
+
+// 僵局处理方式：强制补充版图后仍没有必选动作时
+const (
+	StalematePass  = "pass"  // 跳过该玩家的回合，所有玩家连续被跳过时按分数结束游戏
+	StalemateScore = "score" // 立即按分数结束游戏
+)
+
 // 房间
 type Room struct {
 	ID        string    `json:"id"`
@@ -170,6 +220,7 @@ type APIResponse struct {
 type CreateRoomRequest struct {
 	RoomName   string `json:"roomName" binding:"required"`
 	PlayerName string `json:"playerName" binding:"required"`
+	RuleSet    string `json:"ruleSet,omitempty"` // 规则预设名，为空时使用官方规则
 }
 
 // 加入房间请求
@@ -180,14 +231,49 @@ type JoinRoomRequest struct {
 
 // 创建房间响应
 type CreateRoomResponse struct {
-	Room     Room   `json:"room"`
-	PlayerID string `json:"playerId"`
+	Room         Room   `json:"room"`
+	PlayerID     string `json:"playerId"`
+	SessionToken string `json:"sessionToken"` // 连接 WebSocket 与调用需要身份的接口时携带
 }
 
 // 加入房间响应
 type JoinRoomResponse struct {
-	Room     Room   `json:"room"`
-	PlayerID string `json:"playerId"`
+	Room         Room   `json:"room"`
+	PlayerID     string `json:"playerId"`
+	SessionToken string `json:"sessionToken"` // 连接 WebSocket 与调用需要身份的接口时携带
+}
+
+// 版图坐标
+type Coord struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+// 待决策类型
+type DecisionType string
+
+const (
+	DecisionExtraToken DecisionType = "extra_token" // 从版图拿取一个指定颜色的token
+	DecisionSteal      DecisionType = "steal"       // 从对手处窃取一个非黄金token
+	DecisionWildcard   DecisionType = "wildcard"    // 为百搭卡选择颜色
+	DecisionNoble      DecisionType = "noble"       // 选择一个贵族
+)
+
+// 决策选项，根据决策类型只填写其中一项
+type DecisionOption struct {
+	Position *Coord  `json:"position,omitempty"` // 额外token：版图位置
+	Gem      GemType `json:"gem,omitempty"`      // 窃取：宝石类型；百搭：颜色
+	NobleID  string  `json:"nobleId,omitempty"`  // 贵族ID
+}
+
+// 待处理的玩家决策（由服务端在购买后开启）
+type PendingDecision struct {
+	Type     DecisionType     `json:"type"`
+	PlayerID string           `json:"playerId"`
+	CardID   string           `json:"cardId,omitempty"`  // 触发决策的发展卡
+	NobleID  string           `json:"nobleId,omitempty"` // 触发决策的贵族（如 noble1 的窃取）
+	Color    GemType          `json:"color,omitempty"`   // 额外token要求的颜色
+	Options  []DecisionOption `json:"options"`           // 服务端列出的合法选项
 }
 
 // 待补充的发展卡信息
@@ -202,8 +288,17 @@ type WSMessage struct {
 	PlayerID   string      `json:"playerId,omitempty"`
 	PlayerName string      `json:"playerName,omitempty"`
 	ActionType string      `json:"actionType,omitempty"`
+	RequestID  string      `json:"requestId,omitempty"` // 客户端生成的请求ID，拒绝回复中原样返回
 	Data       any `json:"data,omitempty"`
 	Message    string      `json:"message,omitempty"`
 	Action     *GameAction `json:"action,omitempty"`
 	GameState  *GameState  `json:"gameState,omitempty"`
 }
+
+// 动作被拒绝的回复内容（仅发送给发起动作的客户端）
+type ActionRejection struct {
+	RequestID  string `json:"requestId,omitempty"`
+	ActionType string `json:"actionType"`
+	Code       string `json:"code"`    // 稳定的原因代码，如 NOT_YOUR_TURN
+	Message    string `json:"message"` // 展示给玩家的说明
+}
@@ -0,0 +1,68 @@
+package models
+
+import "fmt"
+
+// HiddenCardID 隐藏卡牌的占位ID，保留等级信息以便显示对应的牌背
+func HiddenCardID(level CardLevel) string {
+	return fmt.Sprintf("hidden_level_%d", level)
+}
+
+// ViewFor 返回发给指定玩家的状态投影：牌堆只保留数量（UnflippedCards），
+// 对手从牌堆盲抽保留的卡在打出前替换为占位ID，随机种子在游戏结束前不下发（只下发承诺值）；
+// playerID 不是本局玩家时返回观战视角
+func (gs GameState) ViewFor(playerID string) GameState {
+	isPlayer := false
+	for _, player := range gs.Players {
+		if player.ID == playerID {
+			isPlayer = true
+			break
+		}
+	}
+	if !isPlayer {
+		return gs.SpectatorView()
+	}
+	return gs.redacted(playerID)
+}
+
+// SpectatorView 返回观战视角：所有玩家盲抽保留的卡都被隐藏
+func (gs GameState) SpectatorView() GameState {
+	return gs.redacted("")
+}
+
+// 隐藏牌堆顺序、未结束对局的随机种子以及除 viewerID 之外玩家的盲抽保留卡；不修改原状态
+func (gs GameState) redacted(viewerID string) GameState {
+	view := gs
+	view.Level1Deck = nil
+	view.Level2Deck = nil
+	view.Level3Deck = nil
+	if gs.Status != GameStatusFinished {
+		view.Seed = 0
+	}
+
+	view.Players = make([]Player, len(gs.Players))
+	for i, player := range gs.Players {
+		if player.ID != viewerID && len(player.BlindReservedCards) > 0 {
+			hidden := make(map[string]bool, len(player.BlindReservedCards))
+			for _, cardID := range player.BlindReservedCards {
+				hidden[cardID] = true
+			}
+			reserved := make([]string, len(player.ReservedCards))
+			for j, cardID := range player.ReservedCards {
+				if hidden[cardID] {
+					cardID = HiddenCardID(gs.CardDetails[cardID].Level)
+				}
+				reserved[j] = cardID
+			}
+			player.ReservedCards = reserved
+			player.BlindReservedCards = nil
+		}
+		view.Players[i] = player
+	}
+	return view
+}
+
+// ViewFor 返回发给指定玩家的房间投影，游戏状态见 GameState.ViewFor
+func (r Room) ViewFor(playerID string) Room {
+	r.GameState = r.GameState.ViewFor(playerID)
+	return r
+}
@@ -0,0 +1,67 @@
+// Package session 签发与校验玩家会话令牌：令牌绑定房间与玩家，用 HMAC-SHA256 签名
+package session
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+// ErrInvalidToken 令牌缺失、格式错误、签名不符或不属于该房间
+var ErrInvalidToken = errors.New("会话令牌无效")
+
+// Signer 使用服务端密钥签发和校验会话令牌
+type Signer struct {
+	secret []byte
+}
+
+// NewSigner 使用指定密钥创建签名器
+func NewSigner(secret []byte) *Signer {
+	return &Signer{secret: append([]byte(nil), secret...)}
+}
+
+// NewRandomSigner 使用随机密钥创建签名器，服务重启后之前签发的令牌失效
+func NewRandomSigner() *Signer {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		panic("生成会话密钥失败: " + err.Error())
+	}
+	return &Signer{secret: secret}
+}
+
+// Issue 为房间内的玩家签发令牌，格式为 base64(房间ID\n玩家ID).base64(签名)
+func (s *Signer) Issue(roomID, playerID string) string {
+	payload := []byte(roomID + "\n" + playerID)
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(s.sign(payload))
+}
+
+// Verify 校验令牌属于该房间，返回令牌绑定的玩家ID
+func (s *Signer) Verify(token, roomID string) (string, error) {
+	encodedPayload, encodedSignature, ok := strings.Cut(token, ".")
+	if !ok {
+		return "", ErrInvalidToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return "", ErrInvalidToken
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(encodedSignature)
+	if err != nil || !hmac.Equal(signature, s.sign(payload)) {
+		return "", ErrInvalidToken
+	}
+
+	tokenRoomID, playerID, ok := strings.Cut(string(payload), "\n")
+	if !ok || tokenRoomID != roomID || playerID == "" {
+		return "", ErrInvalidToken
+	}
+	return playerID, nil
+}
+
+func (s *Signer) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
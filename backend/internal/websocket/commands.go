@@ -0,0 +1,384 @@
+package websocket
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"splendor-duel-backend/internal/game"
+	"splendor-duel-backend/internal/models"
+)
+
+// 前端消息 data 字段的解码与校验层：游戏逻辑只接收类型化的命令
+
+const boardSize = 5
+
+// 前端发送的坐标，使用指针区分缺失字段与 0
+type wireCoord struct {
+	X *int `json:"x"`
+	Y *int `json:"y"`
+}
+
+type wireTakeGems struct {
+	GemPositions []wireCoord `json:"gemPositions"`
+}
+
+type wireSpendPrivilege struct {
+	PrivilegeCount *int        `json:"privilegeCount"`
+	GemPositions   []wireCoord `json:"gemPositions"`
+}
+
+type wireReserveCard struct {
+	CardID *string `json:"cardId"`
+	GoldX  *int    `json:"goldX"`
+	GoldY  *int    `json:"goldY"`
+}
+
+type wireBuyCard struct {
+	CardID      *string        `json:"cardId"`
+	PaymentPlan map[string]int `json:"paymentPlan"`
+	AutoPay     bool           `json:"autoPay"`
+	Effects     struct {
+		ExtraToken *struct {
+			SelectedGem *wireCoord `json:"selectedGem"`
+		} `json:"extraToken"`
+		Steal *struct {
+			GemType string `json:"gemType"`
+		} `json:"steal"`
+		Wildcard *struct {
+			Color string `json:"color"`
+		} `json:"wildcard"`
+		Noble *struct {
+			ID string `json:"id"`
+		} `json:"noble"`
+	} `json:"effects"`
+}
+
+type wireDiscardGem struct {
+	GemType *string `json:"gemType"`
+}
+
+type wireDiscardGemsBatch struct {
+	GemDiscards map[string]int `json:"gemDiscards"`
+}
+
+type wireDecisionOption struct {
+	Position *wireCoord `json:"position"`
+	Gem      string     `json:"gem"`
+	NobleID  string     `json:"nobleId"`
+}
+
+type wireResolveDecision struct {
+	Choice *wireDecisionOption `json:"choice"`
+}
+
+// decodeData 将消息中的 data 字段解码到目标结构
+func decodeData(raw any, v any) error {
+	if raw == nil {
+		return errors.New("缺少动作数据")
+	}
+	bytes, err := json.Marshal(raw)
+	if err != nil {
+		return fmt.Errorf("动作数据无效: %v", err)
+	}
+	if err := json.Unmarshal(bytes, v); err != nil {
+		return fmt.Errorf("动作数据格式错误: %v", err)
+	}
+	return nil
+}
+
+// 校验坐标字段齐全且位于版图内
+func (c *wireCoord) toCoord() (models.Coord, error) {
+	if c == nil || c.X == nil || c.Y == nil {
+		return models.Coord{}, errors.New("缺少坐标")
+	}
+	if *c.X < 0 || *c.X >= boardSize || *c.Y < 0 || *c.Y >= boardSize {
+		return models.Coord{}, fmt.Errorf("坐标 (%d,%d) 超出版图范围", *c.X, *c.Y)
+	}
+	return models.Coord{X: *c.X, Y: *c.Y}, nil
+}
+
+func toCoords(wire []wireCoord) ([]models.Coord, error) {
+	coords := make([]models.Coord, 0, len(wire))
+	seen := map[models.Coord]bool{}
+	for i := range wire {
+		coord, err := wire[i].toCoord()
+		if err != nil {
+			return nil, err
+		}
+		if seen[coord] {
+			return nil, fmt.Errorf("坐标 (%d,%d) 重复", coord.X, coord.Y)
+		}
+		seen[coord] = true
+		coords = append(coords, coord)
+	}
+	return coords, nil
+}
+
+// 校验宝石类型
+func toGemType(s string, allowGold bool) (models.GemType, error) {
+	switch gem := models.GemType(s); gem {
+	case models.GemWhite, models.GemBlue, models.GemGreen, models.GemRed, models.GemBlack, models.GemPearl:
+		return gem, nil
+	case models.GemGold:
+		if allowGold {
+			return gem, nil
+		}
+	}
+	return "", fmt.Errorf("无效的宝石类型: %q", s)
+}
+
+// 校验宝石数量表：类型合法且数量非负，0 的项会被去掉
+func toGemCounts(wire map[string]int) (map[models.GemType]int, error) {
+	counts := make(map[models.GemType]int)
+	for key, count := range wire {
+		gem, err := toGemType(key, true)
+		if err != nil {
+			return nil, err
+		}
+		if count < 0 {
+			return nil, fmt.Errorf("宝石数量不能为负: %s", key)
+		}
+		if count > 0 {
+			counts[gem] = count
+		}
+	}
+	return counts, nil
+}
+
+func toDecisionOption(wire wireDecisionOption) (models.DecisionOption, error) {
+	option := models.DecisionOption{NobleID: wire.NobleID}
+	if wire.Position != nil {
+		coord, err := wire.Position.toCoord()
+		if err != nil {
+			return option, err
+		}
+		option.Position = &coord
+	}
+	if wire.Gem != "" {
+		gem, err := toGemType(wire.Gem, false)
+		if err != nil {
+			return option, err
+		}
+		option.Gem = gem
+	}
+	return option, nil
+}
+
+func decodeTakeGems(raw any) (game.TakeGemsCmd, error) {
+	var wire wireTakeGems
+	if err := decodeData(raw, &wire); err != nil {
+		return game.TakeGemsCmd{}, err
+	}
+	if len(wire.GemPositions) < 1 || len(wire.GemPositions) > 3 {
+		return game.TakeGemsCmd{}, errors.New("只能拿取1-3个宝石")
+	}
+	positions, err := toCoords(wire.GemPositions)
+	if err != nil {
+		return game.TakeGemsCmd{}, err
+	}
+	return game.TakeGemsCmd{Positions: positions}, nil
+}
+
+func decodeSpendPrivilege(raw any) (game.SpendPrivilegeCmd, error) {
+	var wire wireSpendPrivilege
+	if err := decodeData(raw, &wire); err != nil {
+		return game.SpendPrivilegeCmd{}, err
+	}
+	if wire.PrivilegeCount == nil {
+		return game.SpendPrivilegeCmd{}, errors.New("缺少特权数量")
+	}
+	if *wire.PrivilegeCount != len(wire.GemPositions) {
+		return game.SpendPrivilegeCmd{}, errors.New("选择的宝石数量与特权数量不匹配")
+	}
+	positions, err := toCoords(wire.GemPositions)
+	if err != nil {
+		return game.SpendPrivilegeCmd{}, err
+	}
+	return game.SpendPrivilegeCmd{Positions: positions}, nil
+}
+
+func decodeReserveCard(raw any) (game.ReserveCardCmd, error) {
+	var wire wireReserveCard
+	if err := decodeData(raw, &wire); err != nil {
+		return game.ReserveCardCmd{}, err
+	}
+	if wire.CardID == nil || *wire.CardID == "" {
+		return game.ReserveCardCmd{}, errors.New("缺少卡牌ID")
+	}
+	gold, err := (&wireCoord{X: wire.GoldX, Y: wire.GoldY}).toCoord()
+	if err != nil {
+		return game.ReserveCardCmd{}, fmt.Errorf("黄金位置无效: %v", err)
+	}
+
+	cmd := game.ReserveCardCmd{Gold: gold}
+	// cardId 形如 deck_level_X 时表示从该等级牌堆盲抽
+	if levelStr, ok := strings.CutPrefix(*wire.CardID, "deck_level_"); ok {
+		level, err := strconv.Atoi(levelStr)
+		if err != nil || level < 1 || level > 3 {
+			return game.ReserveCardCmd{}, errors.New("无效的牌堆等级信息")
+		}
+		cmd.DeckLevel = models.CardLevel(level)
+	} else {
+		cmd.CardID = *wire.CardID
+	}
+	return cmd, nil
+}
+
+func decodeBuyCard(raw any) (game.BuyCardCmd, error) {
+	var wire wireBuyCard
+	if err := decodeData(raw, &wire); err != nil {
+		return game.BuyCardCmd{}, err
+	}
+	if wire.CardID == nil || *wire.CardID == "" {
+		return game.BuyCardCmd{}, errors.New("缺少卡牌ID")
+	}
+	payment, err := toGemCounts(wire.PaymentPlan)
+	if err != nil {
+		return game.BuyCardCmd{}, err
+	}
+
+	// 随购买预先提交的效果选择
+	choices := map[models.DecisionType]models.DecisionOption{}
+	effects := wire.Effects
+	if effects.ExtraToken != nil && effects.ExtraToken.SelectedGem != nil {
+		coord, err := effects.ExtraToken.SelectedGem.toCoord()
+		if err != nil {
+			return game.BuyCardCmd{}, err
+		}
+		choices[models.DecisionExtraToken] = models.DecisionOption{Position: &coord}
+	}
+	if effects.Steal != nil && effects.Steal.GemType != "" {
+		gem, err := toGemType(effects.Steal.GemType, false)
+		if err != nil {
+			return game.BuyCardCmd{}, err
+		}
+		choices[models.DecisionSteal] = models.DecisionOption{Gem: gem}
+	}
+	if effects.Wildcard != nil && effects.Wildcard.Color != "" {
+		gem, err := toGemType(effects.Wildcard.Color, false)
+		if err != nil {
+			return game.BuyCardCmd{}, err
+		}
+		choices[models.DecisionWildcard] = models.DecisionOption{Gem: gem}
+	}
+	if effects.Noble != nil && effects.Noble.ID != "" {
+		choices[models.DecisionNoble] = models.DecisionOption{NobleID: effects.Noble.ID}
+	}
+
+	return game.BuyCardCmd{CardID: *wire.CardID, Payment: payment, AutoPay: wire.AutoPay, Choices: choices}, nil
+}
+
+func decodeDiscardGem(raw any) (models.GemType, error) {
+	var wire wireDiscardGem
+	if err := decodeData(raw, &wire); err != nil {
+		return "", err
+	}
+	if wire.GemType == nil {
+		return "", errors.New("缺少宝石类型")
+	}
+	return toGemType(*wire.GemType, true)
+}
+
+func decodeDiscardGemsBatch(raw any) (game.DiscardGemsCmd, error) {
+	var wire wireDiscardGemsBatch
+	if err := decodeData(raw, &wire); err != nil {
+		return game.DiscardGemsCmd{}, err
+	}
+	gems, err := toGemCounts(wire.GemDiscards)
+	if err != nil {
+		return game.DiscardGemsCmd{}, err
+	}
+	if len(gems) == 0 {
+		return game.DiscardGemsCmd{}, errors.New("没有选择要丢弃的宝石")
+	}
+	return game.DiscardGemsCmd{Gems: gems}, nil
+}
+
+func decodeResolveDecision(raw any) (game.ResolveDecisionCmd, error) {
+	var wire wireResolveDecision
+	if err := decodeData(raw, &wire); err != nil {
+		return game.ResolveDecisionCmd{}, err
+	}
+	if wire.Choice == nil {
+		return game.ResolveDecisionCmd{}, errors.New("缺少决策选择")
+	}
+	choice, err := toDecisionOption(*wire.Choice)
+	if err != nil {
+		return game.ResolveDecisionCmd{}, err
+	}
+	return game.ResolveDecisionCmd{Choice: choice}, nil
+}
+
+type wireRespondUndo struct {
+	Accept *bool `json:"accept"`
+}
+
+// decodeRespondUndo 解码对悔棋请求的回应
+func decodeRespondUndo(raw any) (bool, error) {
+	var wire wireRespondUndo
+	if err := decodeData(raw, &wire); err != nil {
+		return false, err
+	}
+	if wire.Accept == nil {
+		return false, errors.New("缺少是否同意悔棋")
+	}
+	return *wire.Accept, nil
+}
+
+// decodeAction 将前端的动作消息解码为游戏动作，数据无效时返回 INVALID_ACTION 错误
+func decodeAction(message models.WSMessage) (game.GameAction, error) {
+	action := game.GameAction{PlayerID: message.PlayerID}
+	var err error
+
+	switch message.ActionType {
+	case "start_game":
+		action.Type = game.ActionStartGame
+	case "takeGems":
+		action.Type = game.ActionTakeGems
+		var cmd game.TakeGemsCmd
+		cmd, err = decodeTakeGems(message.Data)
+		action.TakeGems = &cmd
+	case "spendPrivilege":
+		action.Type = game.ActionSpendPrivilege
+		var cmd game.SpendPrivilegeCmd
+		cmd, err = decodeSpendPrivilege(message.Data)
+		action.SpendPrivilege = &cmd
+	case "refillBoard":
+		action.Type = game.ActionRefillBoard
+	case "reserveCard":
+		action.Type = game.ActionReserveCard
+		var cmd game.ReserveCardCmd
+		cmd, err = decodeReserveCard(message.Data)
+		action.ReserveCard = &cmd
+	case "buyCard":
+		action.Type = game.ActionBuyCard
+		var cmd game.BuyCardCmd
+		cmd, err = decodeBuyCard(message.Data)
+		action.BuyCard = &cmd
+	case "resolveDecision":
+		action.Type = game.ActionResolveDecision
+		var cmd game.ResolveDecisionCmd
+		cmd, err = decodeResolveDecision(message.Data)
+		action.ResolveDecision = &cmd
+	case "discardGem":
+		action.Type = game.ActionDiscardGems
+		var gemType models.GemType
+		gemType, err = decodeDiscardGem(message.Data)
+		action.DiscardGems = &game.DiscardGemsCmd{Gems: map[models.GemType]int{gemType: 1}}
+	case "discardGemsBatch":
+		action.Type = game.ActionDiscardGems
+		var cmd game.DiscardGemsCmd
+		cmd, err = decodeDiscardGemsBatch(message.Data)
+		action.DiscardGems = &cmd
+	default:
+		return action, game.NewActionError(game.ErrUnknownAction, "未知的游戏动作类型: "+message.ActionType)
+	}
+
+	if err != nil {
+		return action, game.NewActionError(game.ErrInvalidAction, err.Error())
+	}
+	return action, nil
+}
@@ -5,8 +5,6 @@ import (
 	"fmt"
 	"log"
 	"net/http"
-	"strconv"
-	"strings"
 	"sync"
 	"time"
 
@@ -16,10 +14,25 @@ import (
 	"github.com/gorilla/websocket"
 )
 
+// 玩家连接使用的 WebSocket 子协议：客户端请求 [sessionProtocol, 会话令牌] 两个子协议，服务端只回应 sessionProtocol。
+// 浏览器无法为 WebSocket 设置 Authorization 头，放在查询参数中又会被访问日志记录，因此令牌通过子协议传递
+const sessionProtocol = "splendor-duel"
+
 var upgrader = websocket.Upgrader{
 	CheckOrigin: func(r *http.Request) bool {
 		return true // 允许所有来源，生产环境应该限制
 	},
+	Subprotocols: []string{sessionProtocol},
+}
+
+// 从 Sec-WebSocket-Protocol 头中读取会话令牌（sessionProtocol 之外的子协议）
+func sessionToken(r *http.Request) string {
+	for _, protocol := range websocket.Subprotocols(r) {
+		if protocol != sessionProtocol {
+			return protocol
+		}
+	}
+	return ""
 }
 
 // Client WebSocket 客户端
@@ -38,7 +51,7 @@ type Room struct {
 	Clients map[*Client]bool
 	Manager *game.Manager
 	mutex   sync.RWMutex
-	// 历史缓存：仅用于客户端重连回放
+	// 历史缓存：仅用于客户端重连回放，创建时从存储加载，新增时同时写入存储
 	ChatMessages []models.ChatMessage
 	GameHistory  []models.GameAction
 }
@@ -47,6 +60,8 @@ type Room struct {
 type Hub struct {
 	Rooms map[string]*Room
 	mutex sync.RWMutex
+	// 只向游戏管理器订阅一次领域事件
+	subscribeOnce sync.Once
 }
 
 // NewHub 创建新的 Hub
@@ -56,8 +71,35 @@ func NewHub() *Hub {
 	}
 }
 
-// HandleWebSocket 处理 WebSocket 连接
+// HandleWebSocket 处理 WebSocket 连接；连接需携带创建/加入房间时签发的会话令牌（见 sessionProtocol），
+// 客户端的玩家身份在此绑定，之后消息中的 playerId 一律忽略
 func HandleWebSocket(w http.ResponseWriter, r *http.Request, roomID string, gameManager *game.Manager) {
+	playerID, err := gameManager.Authenticate(roomID, sessionToken(r))
+	if err != nil {
+		log.Printf("拒绝未认证的 WebSocket 连接（房间 %s）: %v", roomID, err)
+		status := http.StatusUnauthorized
+		if game.CodeOf(err, game.ErrInternal) == game.ErrRoomNotFound {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	serveClient(w, r, roomID, playerID, gameManager)
+}
+
+// HandleSpectatorWebSocket 处理观战连接：不需要会话令牌，只接收观战视角的房间信息、状态更新与历史，
+// 发送的任何消息都会被拒绝
+func HandleSpectatorWebSocket(w http.ResponseWriter, r *http.Request, roomID string, gameManager *game.Manager) {
+	if _, exists := gameManager.RoomView(roomID, ""); !exists {
+		http.Error(w, "房间不存在", http.StatusNotFound)
+		return
+	}
+	serveClient(w, r, roomID, "", gameManager)
+}
+
+// serveClient 升级连接并注册到房间；playerID 为空表示观战者
+func serveClient(w http.ResponseWriter, r *http.Request, roomID, playerID string, gameManager *game.Manager) {
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Printf("WebSocket 升级失败: %v", err)
@@ -66,9 +108,10 @@ func HandleWebSocket(w http.ResponseWriter, r *http.Request, roomID string, game
 
 	// 创建客户端
 	client := &Client{
-		ID:      generateClientID(),
-		RoomID:  roomID,
-		Conn:    conn,
+		ID:       generateClientID(),
+		RoomID:   roomID,
+		PlayerID: playerID,
+		Conn:     conn,
 		Send:    make(chan []byte, 256),
 		Manager: gameManager,
 	}
@@ -98,6 +141,10 @@ func getHub() *Hub {
 
 // getOrCreateRoom 获取或创建房间
 func (h *Hub) getOrCreateRoom(roomID string, gameManager *game.Manager) *Room {
+	h.subscribeOnce.Do(func() {
+		gameManager.Subscribe(h.publishHistory)
+	})
+
 	h.mutex.Lock()
 	defer h.mutex.Unlock()
 
@@ -106,9 +153,11 @@ func (h *Hub) getOrCreateRoom(roomID string, gameManager *game.Manager) *Room {
 	}
 
 	room := &Room{
-		ID:      roomID,
-		Clients: make(map[*Client]bool),
-		Manager: gameManager,
+		ID:           roomID,
+		Clients:      make(map[*Client]bool),
+		Manager:      gameManager,
+		ChatMessages: gameManager.Chat(roomID),
+		GameHistory:  gameManager.History(roomID),
 	}
 
 	h.Rooms[roomID] = room
@@ -123,11 +172,13 @@ func (r *Room) registerClient(client *Client) {
 	r.Clients[client] = true
 	log.Printf("客户端 %s 加入房间 %s", client.ID, r.ID)
 
-	// 发送房间信息
-	r.broadcastToClient(client, models.WSMessage{
-		Type: "room_info",
-		Data: r.Manager.GetRoom(r.ID),
-	})
+	// 发送该玩家视角下的房间信息
+	if view, ok := r.Manager.RoomView(r.ID, client.PlayerID); ok {
+		r.broadcastToClient(client, models.WSMessage{
+			Type: "room_info",
+			Data: view,
+		})
+	}
 
 	// 回放历史（仅此客户端）
 	if len(r.ChatMessages) > 0 || len(r.GameHistory) > 0 {
@@ -190,6 +241,44 @@ func (r *Room) broadcastToAll(message models.WSMessage) {
 	}
 }
 
+// broadcastView 向每个客户端发送按其玩家身份投影的消息，非本局玩家收到观战视角
+func (r *Room) broadcastView(build func(view models.Room) models.WSMessage) {
+	r.mutex.RLock()
+	clients := make([]*Client, 0, len(r.Clients))
+	for client := range r.Clients {
+		clients = append(clients, client)
+	}
+	r.mutex.RUnlock()
+
+	for _, client := range clients {
+		view, ok := r.Manager.RoomView(r.ID, client.PlayerID)
+		if !ok {
+			return
+		}
+		r.broadcastToClient(client, build(view))
+	}
+}
+
+// broadcastGameState 向每个客户端发送其视角下的最新游戏状态
+func (r *Room) broadcastGameState() {
+	r.broadcastView(func(view models.Room) models.WSMessage {
+		return models.WSMessage{
+			Type:      "game_state_update",
+			GameState: &view.GameState,
+		}
+	})
+}
+
+// broadcastGameStart 向每个客户端发送其视角下的房间信息（游戏开始）
+func (r *Room) broadcastGameStart() {
+	r.broadcastView(func(view models.Room) models.WSMessage {
+		return models.WSMessage{
+			Type: "game_start",
+			Data: view,
+		}
+	})
+}
+
 // readPump 读取消息泵
 func (c *Client) readPump() {
 	defer func() {
@@ -253,16 +342,22 @@ func (c *Client) writePump() {
 
 // handleMessage 处理接收到的消息
 func (c *Client) handleMessage(message []byte) {
+	// 任何异常输入都不应导致连接协程崩溃
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("处理消息时发生异常: %v", r)
+		}
+	}()
+
 	var wsMessage models.WSMessage
 	if err := json.Unmarshal(message, &wsMessage); err != nil {
 		log.Printf("消息解析失败: %v", err)
 		return
 	}
 
-	// 设置玩家ID
-	if wsMessage.PlayerID != "" {
-		c.PlayerID = wsMessage.PlayerID
-	}
+	// 玩家身份以连接时的会话为准，不信任消息中携带的 playerId/playerName
+	wsMessage.PlayerID = c.PlayerID
+	wsMessage.PlayerName = c.Manager.PlayerName(c.RoomID, c.PlayerID)
 
 	// 获取房间
 	hub := getHub()
@@ -271,6 +366,12 @@ func (c *Client) handleMessage(message []byte) {
 		return
 	}
 
+	// 观战连接只读
+	if c.PlayerID == "" {
+		c.rejectAction(room, wsMessage, game.NewActionError(game.ErrUnauthorized, "观战连接不能发送消息"))
+		return
+	}
+
 	switch wsMessage.Type {
 	case "player_join":
 		c.handlePlayerJoin(wsMessage, room)
@@ -279,7 +380,7 @@ func (c *Client) handleMessage(message []byte) {
 	case "game_action":
 		c.handleGameAction(wsMessage, room)
 	case "start_game":
-		c.handleStartGame(room)
+		c.handleStartGame(wsMessage, room)
 	default:
 		log.Printf("未知消息类型: %s", wsMessage.Type)
 	}
@@ -287,9 +388,6 @@ func (c *Client) handleMessage(message []byte) {
 
 // handlePlayerJoin 处理玩家加入
 func (c *Client) handlePlayerJoin(message models.WSMessage, room *Room) {
-	// 设置客户端的玩家ID
-	c.PlayerID = message.PlayerID
-	
 	// 广播玩家加入消息
 	room.broadcastToAll(models.WSMessage{
 		Type: "player_joined",
@@ -300,64 +398,38 @@ func (c *Client) handlePlayerJoin(message models.WSMessage, room *Room) {
 	})
 
 	// 更新游戏状态并检查是否应该开始游戏
+	shouldStart := false
 	room.Manager.UpdateRoom(c.RoomID, func(roomData *models.Room) {
-		// 检查玩家是否已经存在
-		playerExists := false
+		// 连接时已校验会话令牌，玩家一定已通过加入房间接口入座，这里只刷新活跃时间
 		for i, player := range roomData.GameState.Players {
 			if player.ID == message.PlayerID {
 				roomData.GameState.Players[i].LastActive = time.Now()
-				playerExists = true
 				break
 			}
 		}
 		
-		// 如果玩家不存在，添加新玩家
-		if !playerExists {
-			roomData.GameState.Players = append(roomData.GameState.Players, models.Player{
-				ID:          message.PlayerID,
-				Name:        message.PlayerName,
-				LastActive:  time.Now(),
-				Gems:        make(map[models.GemType]int),
-				Bonus:       make(map[models.GemType]int),
-				ReservedCards: []string{},
-				Crowns:      0,
-				PrivilegeTokens: 0,
-				Points:      0,
-			})
-		}
-		
 		// 检查是否应该自动开始游戏（当有2个玩家且状态为waiting时）
 		if len(roomData.GameState.Players) >= 2 && roomData.GameState.Status == models.GameStatusWaiting {
 			log.Printf("房间 %s 有 %d 个玩家，自动开始游戏", c.RoomID, len(roomData.GameState.Players))
-			
-			// 创建游戏逻辑实例并开始游戏
-			gl := game.NewGameLogic(&roomData.GameState, room.Manager)
-			if err := gl.StartGame(); err != nil {
-				log.Printf("自动开始游戏失败: %v", err)
-				return
-			}
-			
-			roomData.GameState.StartedAt = time.Now()
-			log.Printf("游戏已自动开始")
+			shouldStart = true
 		}
 	})
 
-	// 获取最新的游戏状态
-	latestRoom := room.Manager.GetRoom(c.RoomID)
-	latestGameState := latestRoom.GameState
-	
+	// 通过房间管理器开始游戏，开局会进入公平性记录并公布种子承诺
+	if shouldStart {
+		if _, err := room.Manager.ApplyAction(c.RoomID, game.GameAction{Type: game.ActionStartGame}); err != nil {
+			log.Printf("自动开始游戏失败: %v", err)
+		} else {
+			log.Printf("游戏已自动开始")
+		}
+	}
+
 	// 广播更新后的游戏状态
-	room.broadcastToAll(models.WSMessage{
-		Type:      "game_state_update",
-		GameState: &latestGameState,
-	})
+	room.broadcastGameState()
 	
 	// 如果游戏已开始，广播游戏开始消息
-	if latestGameState.Status == models.GameStatusPlaying {
-		room.broadcastToAll(models.WSMessage{
-			Type: "game_start",
-			Data: latestRoom,
-		})
+	if room.Manager.GetRoom(c.RoomID).GameState.Status == models.GameStatusPlaying {
+		room.broadcastGameStart()
 	}
 }
 
@@ -375,6 +447,7 @@ func (c *Client) handleChatMessage(message models.WSMessage, room *Room) {
 	room.mutex.Lock()
 	room.ChatMessages = append(room.ChatMessages, chatMessage)
 	room.mutex.Unlock()
+	room.Manager.AppendChat(room.ID, chatMessage)
 
 	// 广播聊天消息
 	room.broadcastToAll(models.WSMessage{
@@ -415,6 +488,7 @@ func broadcastHistory(room *Room, playerID, playerName, desc, html string) {
 	room.mutex.Lock()
 	room.GameHistory = append(room.GameHistory, ga)
 	room.mutex.Unlock()
+	room.Manager.AppendHistory(room.ID, ga)
 
 	room.broadcastToAll(models.WSMessage{ Type: "game_action", Action: &ga })
 }
@@ -426,340 +500,124 @@ func (c *Client) handleGameAction(message models.WSMessage, room *Room) {
 	// 安全检查：确保Data不为nil
 	if message.Data == nil {
 		log.Printf("警告: 游戏动作数据为nil，跳过处理")
+		c.rejectAction(room, message, game.NewActionError(game.ErrInvalidAction, "缺少动作数据"))
 		return
 	}
 	
-	// 尝试将Data转换为map[string]any
-	data, ok := message.Data.(map[string]any)
-	if !ok {
-		log.Printf("警告: 无法将Data转换为map[string]any，跳过处理")
+	// 前端发送的actionType在消息的顶层，data在消息的data字段中
+	if message.ActionType == "" {
+		log.Printf("无法获取actionType: %+v", message.ActionType)
+		c.rejectAction(room, message, game.NewActionError(game.ErrInvalidAction, "缺少动作类型"))
 		return
 	}
 	
-	// 执行游戏逻辑
-	room.Manager.UpdateRoom(c.RoomID, func(roomData *models.Room) {
-		// 创建游戏逻辑实例
-		gl := game.NewGameLogic(&roomData.GameState, room.Manager)
-		
-		// 根据动作类型执行相应的游戏逻辑
-		// 前端发送的actionType在消息的顶层，data在消息的data字段中
-		actionType := message.ActionType
-		if actionType == "" {
-			log.Printf("无法获取actionType: %+v", message.ActionType)
-			return
-		}
-		
-		log.Printf("解析到actionType: %s", actionType)
-		
-		switch actionType {
-		case "start_game":
-			log.Printf("执行开始游戏操作")
-			if roomData.GameState.Status == models.GameStatusPlaying {
-				log.Printf("游戏已经开始，跳过重复开始操作")
-				return
-			}
-			if len(roomData.GameState.Players) >= 2 {
-				if err := gl.StartGame(); err != nil {
-					log.Printf("开始游戏失败: %v", err)
-					return
-				}
-				roomData.GameState.StartedAt = time.Now()
-				log.Printf("游戏已手动开始")
-			} else {
-				log.Printf("玩家数量不足，无法开始游戏")
-				return
-			}
-		case "takeGems":
-			if gemPositions, ok := data["gemPositions"].([]any); ok {
-				log.Printf("执行拿取宝石操作，位置: %+v", gemPositions)
-				var positions []map[string]any
-				for _, pos := range gemPositions { if posMap, ok := pos.(map[string]any); ok { positions = append(positions, posMap) } }
-				// 预生成图片与类型（使用操作前的版图）
-				var pics []string
-				var types []string
-				for _, p := range positions {
-					x := int(p["x"].(float64)); y := int(p["y"].(float64))
-					g := string(roomData.GameState.GemBoard[x][y])
-					types = append(types, g)
-					pics = append(pics, histGemImg(g))
-				}
-				if err := gl.TakeGems(message.PlayerID, positions); err != nil {
-					log.Printf("拿取宝石失败: %v", err)
-				} else {
-					// 检查是否触发让对手获得特权条件：3同色（非gold）或包含2枚珍珠
-					grant := false
-					if len(types) == 3 {
-						same := (types[0] == types[1] && types[1] == types[2] && types[0] != "gold")
-						grant = grant || same
-					}
-					pearl := 0
-					for _, t := range types { if t == "pearl" { pearl++ } }
-					if pearl >= 2 { grant = true }
-					html := fmt.Sprintf("拿取宝石：%s", strings.Join(pics, ""))
-					if grant { html += "，允许对手获取一个特权指示物" }
-					desc := "拿取宝石"
-					broadcastHistory(room, message.PlayerID, message.PlayerName, desc, html)
-				}
-			}
-		case "buyCard":
-			if cardID, ok := data["cardId"].(string); ok {
-				log.Printf("执行购买发展卡操作，卡牌ID: %s", cardID)
-				// 预处理：支付、特效与来源
-				paymentPlan, _ := data["paymentPlan"].(map[string]any)
-				var pics []string
-				totalPay := 0
-				for k, v := range paymentPlan {
-					if cnt, ok := v.(float64); ok {
-						c := int(cnt); totalPay += c
-						for i:=0;i<c;i++{ pics = append(pics, histGemImg(k)) }
-					}
-				}
-				// 查找当前玩家索引
-				idx := -1
-				for i, p := range roomData.GameState.Players { if p.ID == message.PlayerID { idx = i; break } }
-				if idx < 0 { idx = 0 }
-				before := roomData.GameState.Players[idx]
-				wasReserved := false
-				for _, rc := range before.ReservedCards { if rc == cardID { wasReserved = true; break } }
-				// 预取特效信息
-				effects, _ := data["effects"].(map[string]any)
-				var extraPic string
-				if extraRaw, ok := effects["extraToken"].(map[string]any); ok {
-					if sel, ok := extraRaw["selectedGem"].(map[string]any); ok {
-						x := int(sel["x"].(float64)); y := int(sel["y"].(float64))
-						g := roomData.GameState.GemBoard[x][y]
-						extraPic = histGemImg(string(g))
-					}
-				}
-				stealGem := ""
-				if stealRaw, ok := effects["steal"].(map[string]any); ok {
-					if gs, ok := stealRaw["gemType"].(string); ok { stealGem = gs }
-				}
-				wildColor := ""
-				if wildRaw, ok := effects["wildcard"].(map[string]any); ok {
-					if cs, ok := wildRaw["color"].(string); ok { wildColor = cs }
-				}
-				nobleId := ""
-				if nobleRaw, ok := effects["noble"].(map[string]any); ok { if nid, ok := nobleRaw["id"].(string); ok { nobleId = nid } }
-				// 执行购买
-				if err := gl.BuyCardWithPaymentPlanAndEffects(message.PlayerID, data); err != nil {
-					log.Printf("购买发展卡失败: %v", err)
-				} else {
-					// 组装购买历史
-					cd := roomData.GameState.CardDetails[cardID]
-					level := int(cd.Level)
-					var html, desc string
-					if totalPay <= 0 {
-						html = fmt.Sprintf("免费拿取一张等级 %d 的%s", level, histCardLink(cardID))
-						desc = "免费拿取发展卡"
-					} else {
-						source := "购买一张"
-						if wasReserved { source = "从保留的发展卡购买一张" }
-						html = fmt.Sprintf("花费 %s，%s等级 %d 的%s", strings.Join(pics, ""), source, level, histCardLink(cardID))
-						desc = "购买发展卡"
-					}
-					broadcastHistory(room, message.PlayerID, message.PlayerName, desc, html)
-					// 获得贵族
-					if nobleId != "" {
-						// 判定是第3还是第6皇冠（根据已有贵族数量）
-						owned := len(before.Nobles)
-						threshold := 3; if owned >= 1 { threshold = 6 }
-						broadcastHistory(room, message.PlayerID, message.PlayerName, "获得贵族", fmt.Sprintf("因皇冠数达到 %d 获得%s", threshold, histNobleLink(nobleId)))
-					}
-					// 特殊效果历史
-					// 额外token
-					if extraPic != "" {
-						broadcastHistory(room, message.PlayerID, message.PlayerName, "额外token", fmt.Sprintf("因发展卡效果，拿取额外的 %s", extraPic))
-					}
-					// 窃取
-					if stealGem != "" {
-						src := "发展卡效果"; if nobleId == "noble1" { src = "贵族效果" }
-						broadcastHistory(room, message.PlayerID, message.PlayerName, "窃取", fmt.Sprintf("因%s，从对手处拿取一枚 %s", src, histGemImg(stealGem)))
-					}
-					// 百搭颜色
-					if wildColor != "" {
-						cn := map[string]string{"white":"白色","blue":"蓝色","green":"绿色","red":"红色","black":"黑色"}[wildColor]
-						broadcastHistory(room, message.PlayerID, message.PlayerName, "百搭颜色", fmt.Sprintf("将百搭颜色卡放置在%s组中", cn))
-					}
-					// 新的回合/获取特权
-					// 依据卡效果或贵族
-					effArr := cd.Effects
-					for _, e := range effArr {
-						if e == models.NewTurn { broadcastHistory(room, message.PlayerID, message.PlayerName, "新的回合", "因发展卡效果，获得额外的回合") }
-						if e == models.GetPrivilege { broadcastHistory(room, message.PlayerID, message.PlayerName, "获得特权", "因发展卡效果，获得一个特权指示物") }
-					}
-					if nobleId == "noble2" {
-						broadcastHistory(room, message.PlayerID, message.PlayerName, "新的回合", "因贵族效果，获得额外的回合")
-					}
-					if nobleId == "noble3" {
-						broadcastHistory(room, message.PlayerID, message.PlayerName, "获得特权", "因贵族效果，获得一个特权指示物")
-					}
-				}
-			}
-		case "reserveCard":
-			if cardID, ok := data["cardId"].(string); ok {
-				log.Printf("执行保留发展卡操作，卡牌ID: %s", cardID)
-				var goldX, goldY int
-				if goldXVal, ok := data["goldX"].(float64); ok { goldX = int(goldXVal) }
-				if goldYVal, ok := data["goldY"].(float64); ok { goldY = int(goldYVal) }
-				// 执行前后比较找出真实卡ID
-				// 查找当前玩家索引
-				idx := -1
-				for i, p := range roomData.GameState.Players { if p.ID == message.PlayerID { idx = i; break } }
-				if idx < 0 { idx = 0 }
-				before := roomData.GameState.Players[idx].ReservedCards
-				if err := gl.ReserveCard(message.PlayerID, cardID, goldX, goldY); err != nil {
-					log.Printf("保留发展卡失败: %v", err)
-				} else {
-					after := roomData.GameState.Players[idx].ReservedCards
-					actual := ""
-					m := map[string]bool{}
-					for _, id := range before { m[id] = true }
-					for _, id := range after { if !m[id] { actual = id; break } }
-					if actual == "" && len(after) > 0 { actual = after[len(after)-1] }
-					// 区分来源：若 cardID 形如 deck_level_X，则为从牌堆保留，隐藏具体卡信息
-					if strings.HasPrefix(cardID, "deck_level_") {
-						lvlStr := strings.TrimPrefix(cardID, "deck_level_")
-						level := 0
-						if v, err := strconv.Atoi(lvlStr); err == nil { level = v }
-						html := fmt.Sprintf("从牌堆保留一张等级 %d 的发展卡，并获得 1 枚黄金", level)
-						desc := "保留发展卡并获得黄金"
-						broadcastHistory(room, message.PlayerID, message.PlayerName, desc, html)
-					} else {
-						level := 0
-						if cd, ok := roomData.GameState.CardDetails[actual]; ok { level = int(cd.Level) }
-						html := fmt.Sprintf("保留一张等级 %d 的%s，并获得 1 枚黄金", level, histCardLink(actual))
-						desc := "保留发展卡并获得黄金"
-						broadcastHistory(room, message.PlayerID, message.PlayerName, desc, html)
-					}
-				}
-			}
-		case "spendPrivilege":
-			if privilegeCount, ok := data["privilegeCount"].(float64); ok {
-				log.Printf("执行花费特权操作，特权数量: %f", privilegeCount)
-				if gemPositions, ok := data["gemPositions"].([]any); ok {
-					var positions []map[string]any
-					for _, pos := range gemPositions { if posMap, ok := pos.(map[string]any); ok { positions = append(positions, posMap) } }
-					var inner []string
-					for _, p := range positions { x := int(p["x"].(float64)); y := int(p["y"].(float64)); g := roomData.GameState.GemBoard[x][y]; inner = append(inner, histGemImg(string(g))) }
-					if err := gl.SpendPrivilege(message.PlayerID, int(privilegeCount), positions); err != nil {
-						log.Printf("花费特权失败: %v", err)
-					} else {
-						pics := strings.Join(inner, "")
-						html := fmt.Sprintf("花费了 %d 特权指示物，拿取 %s", int(privilegeCount), pics)
-						desc := "花费特权"
-						broadcastHistory(room, message.PlayerID, message.PlayerName, desc, html)
-					}
-				}
-			}
-		case "refillBoard":
-			log.Printf("执行补充版图操作")
-			if err := gl.RefillBoard(message.PlayerID); err != nil {
-				log.Printf("补充版图失败: %v", err)
-			} else {
-				log.Printf("补充版图成功")
-				desc := "执行了补充版图，允许对手获取一个特权指示物"
-				broadcastHistory(room, message.PlayerID, message.PlayerName, desc, desc)
-			}
-		case "grantOpponentPrivilege":
-			log.Printf("执行让对手获得特权指示物操作")
-			if err := gl.GrantOpponentPrivilege(message.PlayerID); err != nil {
-				log.Printf("让对手获得特权指示物失败: %v", err)
-			} else {
-				log.Printf("让对手获得特权指示物成功")
-			}
-		case "discardGem":
-			if gemType, ok := data["gemType"].(string); ok {
-				log.Printf("执行丢弃宝石操作，宝石类型: %s", gemType)
-				if err := gl.DiscardGem(message.PlayerID, models.GemType(gemType)); err != nil {
-					log.Printf("丢弃宝石失败: %v", err)
-				} else {
-					log.Printf("丢弃宝石成功")
-					// 记录丢弃宝石，支持单枚
-					pic := histGemImg(gemType)
-					html := fmt.Sprintf("丢弃宝石 %s", pic)
-					desc := "丢弃宝石"
-					broadcastHistory(room, message.PlayerID, message.PlayerName, desc, html)
-				}
-			}
-		case "discardGemsBatch":
-			if gemDiscardsData, ok := data["gemDiscards"].(map[string]interface{}); ok {
-				log.Printf("执行批量丢弃宝石操作，丢弃详情: %v", gemDiscardsData)
-				gemDiscards := make(map[models.GemType]int)
-				for gemTypeStr, count := range gemDiscardsData { if countFloat, ok := count.(float64); ok { gemDiscards[models.GemType(gemTypeStr)] = int(countFloat) } }
-				if err := gl.DiscardGemsBatch(message.PlayerID, gemDiscards); err != nil {
-					log.Printf("批量丢弃宝石失败: %v", err)
-				} else {
-					log.Printf("批量丢弃宝石成功")
-					// 记录批量丢弃
-					var pics []string
-					for gt, ct := range gemDiscards {
-						for i := 0; i < ct; i++ { pics = append(pics, histGemImg(string(gt))) }
-					}
-					html := fmt.Sprintf("丢弃宝石 %s", strings.Join(pics, ""))
-					desc := "丢弃宝石"
-					broadcastHistory(room, message.PlayerID, message.PlayerName, desc, html)
-				}
-			}
-		case "endTurn":
-			log.Printf("执行回合结束操作")
-			if err := gl.HandleTurnEnd(); err != nil {
-				log.Printf("回合结束处理失败: %v", err)
-			} else {
-				log.Printf("回合结束处理成功")
-			}
-		default:
-			log.Printf("未知的游戏动作类型: %s", actionType)
-		}
-		
-		log.Printf("游戏状态已更新")
-	})
+	// 悔棋请求与回应不是游戏动作，由房间管理器处理
+	switch message.ActionType {
+	case "requestUndo":
+		c.handleRequestUndo(message, room)
+		return
+	case "respondUndo":
+		c.handleRespondUndo(message, room)
+		return
+	}
+	
+	action, err := decodeAction(message)
+	if err != nil {
+		log.Printf("游戏动作参数无效: %v", err)
+		c.rejectAction(room, message, err)
+		return
+	}
+	
+	// 执行游戏逻辑；历史记录由领域事件订阅生成，被拒绝的动作只回复发起者且不广播
+	log.Printf("执行游戏动作: %s", action.Type)
+	if _, err := room.Manager.ApplyAction(c.RoomID, action); err != nil {
+		log.Printf("执行游戏动作失败: %v", err)
+		c.rejectAction(room, message, err)
+		return
+	}
+	log.Printf("游戏状态已更新")
 
-	// 获取最新的游戏状态并广播
-	latestRoom := room.Manager.GetRoom(c.RoomID)
-	latestGameState := latestRoom.GameState
-	room.broadcastToAll(models.WSMessage{
-		Type:      "game_state_update",
-		GameState: &latestGameState,
-	})
+	// 向每个客户端广播其视角下的最新游戏状态
+	room.broadcastGameState()
 	
 	// 如果游戏已开始，广播游戏开始消息
-	if latestGameState.Status == models.GameStatusPlaying {
+	if room.Manager.GetRoom(c.RoomID).GameState.Status == models.GameStatusPlaying {
+		room.broadcastGameStart()
+	}
+}
+
+// handleRequestUndo 请求撤销自己的上一步动作，通知房间内所有客户端等待对手回应
+func (c *Client) handleRequestUndo(message models.WSMessage, room *Room) {
+	if err := room.Manager.RequestUndo(c.RoomID, message.PlayerID); err != nil {
+		c.rejectAction(room, message, err)
+		return
+	}
+	room.broadcastToAll(models.WSMessage{
+		Type:       "undo_requested",
+		PlayerID:   message.PlayerID,
+		PlayerName: room.Manager.PlayerName(c.RoomID, message.PlayerID),
+	})
+}
+
+// handleRespondUndo 对手同意或拒绝悔棋；同意时管理器已截断历史，由 ActionUndone 事件重新下发，并广播恢复后的状态
+func (c *Client) handleRespondUndo(message models.WSMessage, room *Room) {
+	accept, err := decodeRespondUndo(message.Data)
+	if err != nil {
+		c.rejectAction(room, message, game.NewActionError(game.ErrInvalidAction, err.Error()))
+		return
+	}
+	undone, err := room.Manager.RespondUndo(c.RoomID, message.PlayerID, accept)
+	if err != nil {
+		c.rejectAction(room, message, err)
+		return
+	}
+	if !undone {
 		room.broadcastToAll(models.WSMessage{
-			Type: "game_start",
-			Data: latestRoom,
+			Type:       "undo_declined",
+			PlayerID:   message.PlayerID,
+			PlayerName: room.Manager.PlayerName(c.RoomID, message.PlayerID),
 		})
+		return
 	}
+
+	room.broadcastGameState()
+}
+
+// rejectAction 向发起动作的客户端回复拒绝原因，并原样返回请求ID
+func (c *Client) rejectAction(room *Room, message models.WSMessage, err error) {
+	rejection := models.ActionRejection{
+		RequestID:  message.RequestID,
+		ActionType: message.ActionType,
+		Code:       string(game.CodeOf(err, game.ErrInternal)),
+		Message:    err.Error(),
+	}
+	log.Printf("动作被拒绝: %+v", rejection)
+	room.broadcastToClient(c, models.WSMessage{
+		Type:       "action_rejected",
+		ActionType: message.ActionType,
+		RequestID:  message.RequestID,
+		Message:    rejection.Message,
+		Data:       rejection,
+	})
 }
 
 // handleStartGame 处理开始游戏
-func (c *Client) handleStartGame(room *Room) {
-	// 使用游戏逻辑来正确初始化游戏
-	room.Manager.UpdateRoom(c.RoomID, func(roomData *models.Room) {
-		// 创建游戏逻辑实例
-		gl := game.NewGameLogic(&roomData.GameState, room.Manager)
-		
-		// 开始游戏（这会初始化宝石版图、发展卡等）
-		if err := gl.StartGame(); err != nil {
-			log.Printf("开始游戏失败: %v", err)
-			return
+func (c *Client) handleStartGame(message models.WSMessage, room *Room) {
+	// 开始游戏（这会初始化宝石版图、发展卡等，并公布种子承诺）
+	if _, err := room.Manager.ApplyAction(c.RoomID, game.GameAction{Type: game.ActionStartGame}); err != nil {
+		// 告诉客户端为什么不能开始（人数不足、已经开始等）
+		if message.ActionType == "" {
+			message.ActionType = string(game.ActionStartGame)
 		}
-		
-		roomData.GameState.StartedAt = time.Now()
-	})
+		c.rejectAction(room, message, err)
+		return
+	}
 
 	// 广播游戏开始消息
-	room.broadcastToAll(models.WSMessage{
-		Type: "game_start",
-		Data: room.Manager.GetRoom(c.RoomID),
-	})
+	room.broadcastGameStart()
 
 	// 广播更新后的游戏状态
-	gameState2 := room.Manager.GetRoom(c.RoomID).GameState
-	room.broadcastToAll(models.WSMessage{
-		Type:      "game_state_update",
-		GameState: &gameState2,
-	})
+	room.broadcastGameState()
 }
 
 // cleanup 清理客户端
@@ -0,0 +1,147 @@
+package websocket
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"splendor-duel-backend/internal/game"
+	"splendor-duel-backend/internal/models"
+)
+
+// 百搭颜色的中文名称
+var wildcardColorNames = map[models.GemType]string{
+	models.GemWhite: "白色",
+	models.GemBlue:  "蓝色",
+	models.GemGreen: "绿色",
+	models.GemRed:   "红色",
+	models.GemBlack: "黑色",
+}
+
+// publishHistory 订阅游戏领域事件，转换为历史记录并广播到对应房间
+func (h *Hub) publishHistory(roomID string, events []game.Event) {
+	h.mutex.RLock()
+	room, exists := h.Rooms[roomID]
+	h.mutex.RUnlock()
+	if !exists {
+		return
+	}
+
+	for _, event := range events {
+		if e, ok := event.(game.ActionUndone); ok {
+			room.reloadHistory()
+			log.Printf("房间 %s 撤销了玩家 %s 的动作 %s", roomID, e.PlayerID, e.Action.Type)
+			continue
+		}
+
+		playerID, desc, html := describeEvent(event)
+		if desc == "" {
+			continue
+		}
+		broadcastHistory(room, playerID, room.Manager.PlayerName(roomID, playerID), desc, html)
+	}
+}
+
+// reloadHistory 悔棋后从管理器重新读取已截断的历史，并重新发给所有客户端
+func (r *Room) reloadHistory() {
+	history := r.Manager.History(r.ID)
+
+	r.mutex.Lock()
+	r.GameHistory = history
+	snapshot := map[string]any{
+		"chat":    r.ChatMessages,
+		"history": r.GameHistory,
+	}
+	r.mutex.Unlock()
+
+	r.broadcastToAll(models.WSMessage{
+		Type: "history_snapshot",
+		Data: snapshot,
+	})
+}
+
+// describeEvent 生成事件的历史记录，返回空描述表示该事件不记录
+func describeEvent(event game.Event) (playerID, desc, html string) {
+	switch e := event.(type) {
+	case game.GemsTaken:
+		if e.CardID != "" {
+			return e.PlayerID, "额外token", fmt.Sprintf("因发展卡效果，拿取额外的 %s", histGems(e.Gems))
+		}
+		html = fmt.Sprintf("拿取宝石：%s", histGems(e.Gems))
+		if e.OpponentPrivilege {
+			html += "，允许对手获取一个特权指示物"
+		}
+		return e.PlayerID, "拿取宝石", html
+	case game.PrivilegeSpent:
+		return e.PlayerID, "花费特权", fmt.Sprintf("花费了 %d 特权指示物，拿取 %s", len(e.Positions), histGems(e.Gems))
+	case game.PrivilegeGained:
+		return e.PlayerID, "获得特权", fmt.Sprintf("因%s，获得一个特权指示物", effectSource(e.NobleID))
+	case game.BoardRefilled:
+		desc = "执行了补充版图，允许对手获取一个特权指示物"
+		if e.Forced {
+			desc = "没有可执行的必选动作，强制补充版图，允许对手获取一个特权指示物"
+		}
+		return e.PlayerID, desc, desc
+	case game.CardReserved:
+		// 从牌堆保留时隐藏具体卡信息
+		if e.FromDeck {
+			return e.PlayerID, "保留发展卡并获得黄金", fmt.Sprintf("从牌堆保留一张等级 %d 的发展卡，并获得 1 枚黄金", e.Level)
+		}
+		return e.PlayerID, "保留发展卡并获得黄金", fmt.Sprintf("保留一张等级 %d 的%s，并获得 1 枚黄金", e.Level, histCardLink(e.CardID))
+	case game.CardPurchased:
+		var pics []string
+		for gem, count := range e.Payment {
+			pics = append(pics, strings.Repeat(histGemImg(string(gem)), count))
+		}
+		if len(pics) == 0 {
+			return e.PlayerID, "免费拿取发展卡", fmt.Sprintf("免费拿取一张等级 %d 的%s", e.Level, histCardLink(e.CardID))
+		}
+		source := "购买一张"
+		if e.FromReserve {
+			source = "从保留的发展卡购买一张"
+		}
+		return e.PlayerID, "购买发展卡", fmt.Sprintf("花费 %s，%s等级 %d 的%s", strings.Join(pics, ""), source, e.Level, histCardLink(e.CardID))
+	case game.TokenStolen:
+		return e.PlayerID, "窃取", fmt.Sprintf("因%s，从对手处拿取一枚 %s", effectSource(e.NobleID), histGemImg(string(e.Gem)))
+	case game.WildcardAssigned:
+		return e.PlayerID, "百搭颜色", fmt.Sprintf("将百搭颜色卡放置在%s组中", wildcardColorNames[e.Color])
+	case game.NobleGained:
+		return e.PlayerID, "获得贵族", fmt.Sprintf("因皇冠数达到 %d 获得%s", e.Threshold, histNobleLink(e.NobleID))
+	case game.ExtraTurnGranted:
+		return e.PlayerID, "新的回合", fmt.Sprintf("因%s，获得额外的回合", effectSource(e.NobleID))
+	case game.GemsDiscarded:
+		var pics []string
+		for gem, count := range e.Gems {
+			pics = append(pics, strings.Repeat(histGemImg(string(gem)), count))
+		}
+		return e.PlayerID, "丢弃宝石", fmt.Sprintf("丢弃宝石 %s", strings.Join(pics, ""))
+	case game.GameWon:
+		return e.PlayerID, "获得胜利", fmt.Sprintf("获得胜利：%s", strings.Join(e.Reasons, "，"))
+	case game.TurnPassed:
+		desc = "没有可执行的必选动作，跳过回合"
+		return e.PlayerID, desc, desc
+	case game.GameDrawn:
+		return "", "平局", fmt.Sprintf("游戏以平局结束：%s", strings.Join(e.Reasons, "，"))
+	case game.SeedCommitted:
+		return "", "种子承诺", fmt.Sprintf("本局随机种子的承诺（SHA-256）：%s", e.Commitment)
+	case game.SeedRevealed:
+		return "", "公开种子", fmt.Sprintf("公开本局随机种子 %d（承诺 %s），可用 cmd/verify 校验洗牌与补充版图", e.Seed, e.Commitment)
+	}
+	return "", "", ""
+}
+
+func histGems(gems []models.GemType) string {
+	var pics []string
+	for _, gem := range gems {
+		pics = append(pics, histGemImg(string(gem)))
+	}
+	return strings.Join(pics, "")
+}
+
+// 效果来源：贵族或发展卡
+func effectSource(nobleID string) string {
+	if nobleID != "" {
+		return "贵族效果"
+	}
+	return "发展卡效果"
+}
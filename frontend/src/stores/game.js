@@ -6,11 +6,21 @@ export const useGameStore = defineStore('game', () => {
   // 状态
   const currentRoom = ref(null)
   const currentPlayer = ref(null)
+  // 创建/加入房间时服务端签发的会话令牌，连接 WebSocket 时携带
+  const sessionToken = ref(null)
   const gameState = ref(null)
   const isConnected = ref(false)
   const chatMessages = ref([])
   const gameHistory = ref([])
   const websocket = ref(null)
+  // 最近一次被服务端拒绝的动作（code/message/requestId）
+  const lastRejection = ref(null)
+  // 等待回应的悔棋请求（playerId/playerName），以及最近一次被拒绝的悔棋
+  const undoRequest = ref(null)
+  const undoDeclined = ref(null)
+  // 会话令牌已失效：服务端未设置固定的 -session-secret 时，重启后旧令牌全部失效，需要重新加入房间
+  const sessionExpired = ref(false)
+  let requestSeq = 0
 
   // 创建房间
   const createRoom = async (roomName, playerName) => {
@@ -32,12 +42,14 @@ export const useGameStore = defineStore('game', () => {
           id: response.data.data.playerId,
           name: playerName
         }
+        sessionToken.value = response.data.data.sessionToken
 
         // 持久化本地会话，便于断线重连
         try {
           const roomId = response.data.data.room.id
           localStorage.setItem(`sd:room:${roomId}:playerId`, response.data.data.playerId)
           localStorage.setItem(`sd:room:${roomId}:playerName`, playerName)
+          localStorage.setItem(`sd:room:${roomId}:token`, response.data.data.sessionToken)
         } catch (e) {
           console.warn('持久化玩家身份失败:', e)
         }
@@ -68,12 +80,14 @@ export const useGameStore = defineStore('game', () => {
           id: response.data.data.playerId,
           name: playerName
         }
+        sessionToken.value = response.data.data.sessionToken
 
         // 持久化本地会话，便于断线重连
         try {
           const roomId = response.data.data.room.id
           localStorage.setItem(`sd:room:${roomId}:playerId`, response.data.data.playerId)
           localStorage.setItem(`sd:room:${roomId}:playerName`, playerName)
+          localStorage.setItem(`sd:room:${roomId}:token`, response.data.data.sessionToken)
         } catch (e) {
           console.warn('持久化玩家身份失败:', e)
         }
@@ -92,10 +106,14 @@ export const useGameStore = defineStore('game', () => {
     // 使用相对路径，让 Caddy/Nginx 处理 WebSocket 升级
     const protocol = window.location.protocol === 'https:' ? 'wss:' : 'ws:'
     const wsUrl = `${protocol}//${window.location.host}/ws/${roomId}`
-    websocket.value = new WebSocket(wsUrl)
+    // 会话令牌作为 WebSocket 子协议发送，避免出现在 URL 与访问日志中
+    const protocols = sessionToken.value ? ['splendor-duel', sessionToken.value] : []
+    websocket.value = new WebSocket(wsUrl, protocols)
+    let opened = false
 
     websocket.value.onopen = () => {
       console.log('WebSocket 连接已建立')
+      opened = true
       isConnected.value = true
       
       // 发送玩家信息
@@ -114,6 +132,10 @@ export const useGameStore = defineStore('game', () => {
     websocket.value.onclose = () => {
       console.log('WebSocket 连接已关闭')
       isConnected.value = false
+      // 升级被拒绝时浏览器拿不到状态码，通过接口确认令牌是否已失效
+      if (!opened) {
+        checkSession(roomId)
+      }
     }
 
     websocket.value.onerror = (error) => {
@@ -122,6 +144,24 @@ export const useGameStore = defineStore('game', () => {
     }
   }
 
+  // 确认会话令牌是否仍然有效；令牌无效（401）或房间已不存在（404）时清除本地保存的会话
+  const checkSession = async (roomId) => {
+    try {
+      await axios.get(`/api/rooms/${roomId}/session`, {
+        headers: { Authorization: `Bearer ${sessionToken.value || ''}` }
+      })
+    } catch (error) {
+      const status = error.response?.status
+      if (status === 401 || status === 404) {
+        console.warn('会话已失效，需要重新加入房间')
+        localStorage.removeItem(`sd:room:${roomId}:playerId`)
+        localStorage.removeItem(`sd:room:${roomId}:playerName`)
+        localStorage.removeItem(`sd:room:${roomId}:token`)
+        sessionExpired.value = true
+      }
+    }
+  }
+
   // 处理 WebSocket 消息
   const handleWebSocketMessage = (data) => {
     console.log('收到WebSocket消息:', data)
@@ -157,6 +197,15 @@ export const useGameStore = defineStore('game', () => {
           gameState.value = data.gameState
           console.log('游戏状态已更新:', gameState.value)
         }
+        // 状态变化后（悔棋完成或有新的动作）悔棋请求失效
+        undoRequest.value = null
+        break
+      case 'undo_requested':
+        undoRequest.value = { playerId: data.playerId, playerName: data.playerName }
+        break
+      case 'undo_declined':
+        undoRequest.value = null
+        undoDeclined.value = { playerId: data.playerId, playerName: data.playerName, at: Date.now() }
         break
       case 'chat_message':
         if (data.message) {
@@ -228,6 +277,10 @@ export const useGameStore = defineStore('game', () => {
       case 'game_end':
         console.log('游戏结束')
         break
+      case 'action_rejected':
+        console.warn('动作被拒绝:', data.data)
+        lastRejection.value = data.data
+        break
       case 'error':
         console.error('服务器错误:', data.message)
         break
@@ -275,6 +328,7 @@ export const useGameStore = defineStore('game', () => {
         playerId: currentPlayer.value.id,
         playerName: currentPlayer.value.name,
         actionType: actionType,
+        requestId: `${currentPlayer.value.id}-${++requestSeq}`,
         data: data
       }
       
@@ -293,6 +347,16 @@ export const useGameStore = defineStore('game', () => {
     }
   }
 
+  // 请求撤销自己的上一步动作（需要对手同意）
+  const requestUndo = () => {
+    sendGameAction('requestUndo', {})
+  }
+
+  // 回应对手的悔棋请求
+  const respondUndo = (accept) => {
+    sendGameAction('respondUndo', { accept })
+  }
+
   // 断开连接
   const disconnect = () => {
     if (websocket.value) {
@@ -302,9 +366,12 @@ export const useGameStore = defineStore('game', () => {
     isConnected.value = false
     currentRoom.value = null
     currentPlayer.value = null
+    sessionToken.value = null
+    sessionExpired.value = false
     gameState.value = null
     chatMessages.value = []
     gameHistory.value = []
+    undoRequest.value = null
   }
 
   // 从本地存储恢复玩家身份（断线重连）
@@ -312,12 +379,14 @@ export const useGameStore = defineStore('game', () => {
     try {
       const storedPlayerId = localStorage.getItem(`sd:room:${roomId}:playerId`)
       const storedPlayerName = localStorage.getItem(`sd:room:${roomId}:playerName`)
-      if (storedPlayerId && storedPlayerName) {
+      const storedToken = localStorage.getItem(`sd:room:${roomId}:token`)
+      if (storedPlayerId && storedPlayerName && storedToken) {
         // 若当前store缺失玩家信息，则恢复
         if (!currentPlayer.value) {
           currentPlayer.value = { id: storedPlayerId, name: storedPlayerName }
         }
-        // 如果未连接，则直接连接WS，服务端会按玩家ID识别为原玩家
+        sessionToken.value = storedToken
+        // 如果未连接，则直接连接WS，服务端按会话令牌识别为原玩家
         if (!isConnected.value) {
           connectWebSocket(roomId)
         }
@@ -338,6 +407,7 @@ export const useGameStore = defineStore('game', () => {
     // 状态
     currentRoom,
     currentPlayer,
+    sessionToken,
     gameState,
     isConnected,
     chatMessages,
@@ -350,6 +420,12 @@ export const useGameStore = defineStore('game', () => {
     sendChatMessage,
     performGameAction,
     sendGameAction,
+    lastRejection,
+    undoRequest,
+    undoDeclined,
+    sessionExpired,
+    requestUndo,
+    respondUndo,
     disconnect,
     reset,
     restoreSession